@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// writeBaseline writes the imports of the graph to a baseline file, one
+// "from -> to" line per import, so that -baseline only reports the imports
+// added since.
+func writeBaseline(name string, g *graph) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	fmt.Fprintln(w, "# imports known when the baseline was written, see godepgraph -baseline")
+	for _, e := range g.edges() {
+		fmt.Fprintf(w, "%s -> %s\n", e.From, e.To)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readBaseline reads a baseline file written with -write-baseline. Blank lines
+// and lines starting with # are ignored.
+func readBaseline(name string) (map[[2]string]bool, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	known := make(map[[2]string]bool)
+	s := bufio.NewScanner(f)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		parts := strings.Split(text, "->")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected an import as from -> to", name, line)
+		}
+		from, to := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if from == "" || to == "" {
+			return nil, fmt.Errorf("%s:%d: expected an import as from -> to", name, line)
+		}
+		known[[2]string{from, to}] = true
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return known, nil
+}
+
+// newImports returns the imports of the graph that aren't in the baseline.
+func (g *graph) newImports(known map[[2]string]bool) []violation {
+	var violations []violation
+	for _, e := range g.edges() {
+		if !known[[2]string{e.From, e.To}] {
+			violations = append(violations, violation{From: e.From, To: e.To, Rule: "not in the baseline"})
+		}
+	}
+	return violations
+}
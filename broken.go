@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// brokenPackages returns the packages of the graph that couldn't be
+// imported, which are only part of the graph with -k.
+func (g *graph) brokenPackages() []*node {
+	var broken []*node
+	for _, n := range g.Nodes {
+		if n.Error != "" {
+			broken = append(broken, n)
+		}
+	}
+	return broken
+}
+
+// reportBroken writes a summary of the packages that couldn't be imported.
+func reportBroken(w io.Writer, broken []*node) {
+	if len(broken) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "failed to import %d package(s):\n", len(broken))
+	for _, n := range broken {
+		fmt.Fprintf(w, "  %s: %s\n", processName(n.ImportPath), n.Error)
+	}
+}
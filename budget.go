@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// budgets returns a description of each dependency budget given on the
+// command line that the graph exceeds.
+func (g *graph) budgets() []string {
+	var exceeded []string
+	if *maxPackages > 0 && len(g.Nodes) > *maxPackages {
+		exceeded = append(exceeded, fmt.Sprintf("%d packages, more than %d", len(g.Nodes), *maxPackages))
+	}
+	if *maxDeps > 0 {
+		for _, n := range g.Nodes {
+			if count := g.transitiveCount(n.ImportPath); count > *maxDeps {
+				exceeded = append(exceeded, fmt.Sprintf("%s has %d transitive dependencies, more than %d", processName(n.ImportPath), count, *maxDeps))
+			}
+		}
+	}
+	if *maxDepth > 0 {
+		for i, chain := range g.longestChains() {
+			if len(chain)-1 > *maxDepth {
+				exceeded = append(exceeded, fmt.Sprintf("%s has an import chain of %d imports, more than %d", processName(g.Roots[i]), len(chain)-1, *maxDepth))
+			}
+		}
+	}
+	return exceeded
+}
+
+// reportBudgets writes a summary of the exceeded dependency budgets.
+func reportBudgets(w io.Writer, exceeded []string) {
+	if len(exceeded) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "exceeded %d dependency budget(s):\n", len(exceeded))
+	for _, e := range exceeded {
+		fmt.Fprintf(w, "  %s\n", e)
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// fileStamp identifies the version of a file by its size and modification
+// time, or by its size and the hash of its contents.
+type fileStamp struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime,omitempty"`
+	Hash    string    `json:"hash,omitempty"`
+}
+
+// cacheEntry is a package resolved by go/build, along with the stamps of the
+// files of its directory at the time, and of the files choosing the module
+// versions of the main module, which must all be unchanged for the entry to
+// be used.
+type cacheEntry struct {
+	Package *build.Package       `json:"package"`
+	Files   map[string]fileStamp `json:"files"`
+	Modules map[string]fileStamp `json:"modules,omitempty"`
+}
+
+// packageCache holds the packages resolved in a previous run with -cache or
+// -incremental, keyed by source directory and import path. The cache is only
+// used with the same build configuration, so that changing the build tags,
+// the platform or the Go version starts from scratch.
+type packageCache struct {
+	file string
+	// hash is true if the files are identified by the hash of their
+	// contents rather than by their modification time.
+	hash  bool
+	dirty bool
+	// modules holds the module stamps of each build context directory,
+	// which are only computed once per run.
+	modules map[string]map[string]fileStamp
+
+	Config  string                 `json:"config"`
+	Entries map[string]*cacheEntry `json:"entries"`
+}
+
+// cache is the package cache used with -cache or -incremental, or nil.
+var cache *packageCache
+
+// openCache loads the package cache of the current build configuration from
+// the user cache directory, where the cache of each configuration is stored
+// in its own file.
+func openCache() (*packageCache, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	config := buildConfig()
+	sum := sha256.Sum256([]byte(config))
+	return loadCache(filepath.Join(dir, "godepgraph", hex.EncodeToString(sum[:])+".json"), false), nil
+}
+
+// loadCache loads a package cache from the named file. A missing or
+// unreadable file, or one written with another build configuration, gives an
+// empty cache.
+func loadCache(file string, hash bool) *packageCache {
+	c := &packageCache{file: file, hash: hash, modules: make(map[string]map[string]fileStamp)}
+	if data, err := ioutil.ReadFile(file); err == nil {
+		json.Unmarshal(data, c)
+	}
+	if c.Config != buildConfig() || c.Entries == nil {
+		c.Config = buildConfig()
+		c.Entries = make(map[string]*cacheEntry)
+	}
+	return c
+}
+
+// buildConfig describes the configuration used to resolve the packages.
+func buildConfig() string {
+	return strings.Join([]string{
+		runtime.Version(),
+		buildContext.GOROOT,
+		buildContext.GOOS,
+		buildContext.GOARCH,
+		fmt.Sprint(buildContext.CgoEnabled),
+		strings.Join(buildContext.BuildTags, ","),
+		os.Getenv("GOFLAGS"),
+		buildContext.Dir,
+	}, "\x00")
+}
+
+// importPackage resolves a package like buildContext.Import, using the
+// packages listed by go list with -golist, or the cache if there is one.
+func importPackage(path, srcDir string) (*build.Package, error) {
+	if listedPackages != nil {
+		if pkg, ok := lookupListed(path, srcDir); ok {
+			return pkg, nil
+		}
+	}
+	if cache == nil {
+		return buildContext.Import(path, srcDir, 0)
+	}
+	// The main module is found from the build context's directory, which
+	// can differ between the packages of a scan.
+	key := buildContext.Dir + "\x00" + srcDir + "\x00" + path
+	mods, ok := cache.modules[buildContext.Dir]
+	if !ok {
+		mods = moduleStamps(buildContext.Dir, cache.hash)
+		cache.modules[buildContext.Dir] = mods
+	}
+	if e, ok := cache.Entries[key]; ok && e.Package != nil && sameStamps(mods, e.Modules) && sameFiles(e.Package.Dir, e.Files, cache.hash) {
+		return e.Package, nil
+	}
+	pkg, err := buildContext.Import(path, srcDir, 0)
+	if err != nil {
+		return nil, err
+	}
+	if files := dirStamps(pkg.Dir, cache.hash); files != nil && mods != nil {
+		cache.Entries[key] = &cacheEntry{Package: pkg, Files: files, Modules: mods}
+		cache.dirty = true
+	}
+	return pkg, nil
+}
+
+// save writes the cache back to disk if it changed.
+func (c *packageCache) save() error {
+	if !c.dirty {
+		return nil
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.file), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.file, data, 0644)
+}
+
+// dirStamps returns the stamps of the files in dir, and of the go.mod file
+// of the module containing it since it can change how the imports are
+// resolved. It returns nil if dir or one of the files can't be read.
+func dirStamps(dir string, hash bool) map[string]fileStamp {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	files := make(map[string]os.FileInfo)
+	for _, info := range infos {
+		if !info.IsDir() {
+			files[filepath.Join(dir, info.Name())] = info
+		}
+	}
+	if m := moduleOf(dir); m != nil && m.Version == "" {
+		gomod := filepath.Join(m.Dir, "go.mod")
+		if info, err := os.Stat(gomod); err == nil {
+			files[gomod] = info
+		}
+	}
+
+	return fileStamps(files, hash)
+}
+
+// moduleStamps returns the stamps of the files choosing the module versions
+// resolved from dir: the go.mod file of its main module, along with the
+// go.work file and the vendor/modules.txt file. Those that don't exist are
+// given a size of -1, so that creating them invalidates the stamps. It
+// returns an empty map if dir isn't in a module, and nil if one of the files
+// can't be read.
+func moduleStamps(dir string, hash bool) map[string]fileStamp {
+	m := moduleOf(dir)
+	if m == nil || m.Version != "" {
+		return make(map[string]fileStamp)
+	}
+	work := findWorkspace(dir)
+	if work == "" {
+		work = filepath.Join(m.Dir, "go.work")
+	}
+	files := make(map[string]os.FileInfo)
+	missing := make(map[string]fileStamp)
+	for _, name := range []string{
+		filepath.Join(m.Dir, "go.mod"),
+		work,
+		filepath.Join(m.Dir, "vendor", "modules.txt"),
+	} {
+		if info, err := os.Stat(name); err == nil {
+			files[name] = info
+		} else {
+			missing[name] = fileStamp{Size: -1}
+		}
+	}
+	stamps := fileStamps(files, hash)
+	if stamps == nil {
+		return nil
+	}
+	for name, s := range missing {
+		stamps[name] = s
+	}
+	return stamps
+}
+
+// fileStamps returns the stamps of the given files, or nil if one of them
+// can't be read.
+func fileStamps(files map[string]os.FileInfo, hash bool) map[string]fileStamp {
+	stamps := make(map[string]fileStamp)
+	for name, info := range files {
+		s := fileStamp{Size: info.Size()}
+		if hash {
+			data, err := ioutil.ReadFile(name)
+			if err != nil {
+				return nil
+			}
+			sum := sha256.Sum256(data)
+			s.Hash = hex.EncodeToString(sum[:])
+		} else {
+			s.ModTime = info.ModTime()
+		}
+		stamps[name] = s
+	}
+	return stamps
+}
+
+// sameFiles reports whether the files of dir still have the given stamps.
+func sameFiles(dir string, stamps map[string]fileStamp, hash bool) bool {
+	return sameStamps(dirStamps(dir, hash), stamps)
+}
+
+// sameStamps reports whether two sets of stamps are the same.
+func sameStamps(current, stamps map[string]fileStamp) bool {
+	if current == nil || len(current) != len(stamps) {
+		return false
+	}
+	for name, s := range stamps {
+		c, ok := current[name]
+		if !ok || c.Size != s.Size || !c.ModTime.Equal(s.ModTime) || c.Hash != s.Hash {
+			return false
+		}
+	}
+	return true
+}
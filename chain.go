@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// longestChain returns the longest import chain starting at p. The imports
+// between the packages of an import cycle aren't followed, so that the chain
+// doesn't go around it.
+func (g *graph) longestChain(p string, inCycle func(from, to string) bool, memo map[string][]string) []string {
+	if chain, ok := memo[p]; ok {
+		return chain
+	}
+	var longest []string
+	for _, imp := range g.node(p).Imports {
+		if inCycle(p, imp) {
+			continue
+		}
+		if chain := g.longestChain(imp, inCycle, memo); len(chain) > len(longest) {
+			longest = chain
+		}
+	}
+	chain := append([]string{p}, longest...)
+	memo[p] = chain
+	return chain
+}
+
+// longestChains returns the longest import chain from each of the root
+// packages.
+func (g *graph) longestChains() [][]string {
+	comp := make(map[string]int)
+	for i, c := range g.sccs() {
+		for _, p := range c {
+			comp[p] = i + 1
+		}
+	}
+	inCycle := func(from, to string) bool {
+		return comp[from] != 0 && comp[from] == comp[to]
+	}
+
+	memo := make(map[string][]string)
+	var chains [][]string
+	for _, root := range g.Roots {
+		chains = append(chains, g.longestChain(root, inCycle, memo))
+	}
+	return chains
+}
+
+// writeChain writes the longest import chains from the root packages, one
+// for each root package from which the longest chain of the graph starts.
+func writeChain(w io.Writer, g *graph) error {
+	var chains [][]string
+	for _, chain := range g.longestChains() {
+		switch {
+		case len(chains) == 0 || len(chain) > len(chains[0]):
+			chains = [][]string{chain}
+		case len(chain) == len(chains[0]):
+			chains = append(chains, chain)
+		}
+	}
+	if len(chains) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(w, "longest import chain: %d import(s)\n", len(chains[0])-1)
+	for _, chain := range chains {
+		var lines []string
+		for i, p := range chain {
+			if i == 0 {
+				lines = append(lines, processName(p))
+			} else {
+				lines = append(lines, fmt.Sprintf("%*simports %s", 2*i, "", processName(p)))
+			}
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(lines, "\n")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
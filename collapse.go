@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// collapseStdlib merges all the packages of the standard library into a
+// single node.
+func (g *graph) collapseStdlib() *graph {
+	count := 0
+	for _, n := range g.Nodes {
+		if n.Goroot {
+			count++
+		}
+	}
+	name := fmt.Sprintf("stdlib (%d packages)", count)
+	return g.merge(func(n *node) string {
+		if n.Goroot {
+			return name
+		}
+		return ""
+	})
+}
+
+// collapsePrefixes merges the packages under each of the given prefixes into
+// a single node. A package under more than one prefix is merged into the
+// group of the longest one.
+func (g *graph) collapsePrefixes(prefixes []string) *graph {
+	groups := make(map[string]string)
+	counts := make(map[string]int)
+	for _, n := range g.Nodes {
+		group := ""
+		for _, p := range prefixes {
+			p = strings.TrimSuffix(strings.TrimSuffix(p, "..."), "/")
+			if (n.ImportPath == p || strings.HasPrefix(n.ImportPath, p+"/")) && len(p) > len(group) {
+				group = p
+			}
+		}
+		if group != "" {
+			groups[n.ImportPath] = group
+			counts[group]++
+		}
+	}
+	return g.merge(func(n *node) string {
+		group, ok := groups[n.ImportPath]
+		if !ok {
+			return ""
+		}
+		if counts[group] == 1 {
+			return group + " (1 package)"
+		}
+		return fmt.Sprintf("%s (%d packages)", group, counts[group])
+	})
+}
+
+// collapseModules merges the packages of each module into a single node
+// named after the module, and those of the standard library into a single
+// node as well. Packages outside of any module are left alone.
+func (g *graph) collapseModules() *graph {
+	counts := make(map[string]int)
+	groupOf := func(n *node) string {
+		switch {
+		case n.Goroot:
+			return "std"
+		case n.Module != "":
+			return n.Module
+		}
+		return ""
+	}
+	for _, n := range g.Nodes {
+		counts[groupOf(n)]++
+	}
+	return g.merge(func(n *node) string {
+		group := groupOf(n)
+		if group == "" {
+			return ""
+		}
+		if counts[group] == 1 {
+			return group + " (1 package)"
+		}
+		return fmt.Sprintf("%s (%d packages)", group, counts[group])
+	})
+}
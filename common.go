@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// writeCommon writes the packages that depend on both a and b, which is
+// where the two are glued together, and whether they import each of them
+// directly or indirectly.
+func writeCommon(w io.Writer, g *graph, a, b string) error {
+	if err := g.checkNodes([]string{a, b}); err != nil {
+		return err
+	}
+	reachA := g.reaching([]string{a})
+	reachB := g.reaching([]string{b})
+	kind := func(n *node, p string) string {
+		if contains(n.Imports, p) {
+			return "direct"
+		}
+		return "indirect"
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "PACKAGE\t%s\t%s\n", processName(a), processName(b))
+	for _, n := range g.Nodes {
+		p := n.ImportPath
+		if p == a || p == b || !reachA[p] || !reachB[p] {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", processName(p), kind(n, a), kind(n, b))
+	}
+	return tw.Flush()
+}
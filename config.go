@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// configFileName is the name of the configuration file looked up in the
+// current directory and its parents, up to the root of the repository.
+const configFileName = ".godepgraph.yaml"
+
+// configSetting is the value of a flag given in a configuration file.
+type configSetting struct {
+	name string
+	line int
+	// values holds the value of the flag, or its items for a list or a map,
+	// where each key: value item is given as key=value.
+	values []string
+	list   bool
+}
+
+// findConfig returns the configuration file in dir or the closest of its
+// parents, stopping at the root of the repository, or "" if there is none.
+func findConfig(dir string) string {
+	for {
+		name := filepath.Join(dir, configFileName)
+		if _, err := os.Stat(name); err == nil {
+			return name
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// readConfig reads a configuration file, written in a subset of YAML where
+// each key is the name of a flag, and its value a scalar, a list or a map:
+//
+//	s: true
+//	p:
+//	  - golang.org/x/
+//	c:
+//	  github.com: red
+//	deny:
+//	  - internal/...=cmd/...
+func readConfig(name string) ([]configSetting, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var settings []configSetting
+	s := bufio.NewScanner(f)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimRight(s.Text(), " \t")
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if trimmed == text {
+			kv := strings.SplitN(text, ":", 2)
+			if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+				return nil, fmt.Errorf("%s:%d: expected flag: value", name, line)
+			}
+			c := configSetting{name: strings.TrimSpace(kv[0]), line: line}
+			if v := configValue(kv[1]); v != "" {
+				c.values = []string{v}
+			} else {
+				c.list = true
+			}
+			settings = append(settings, c)
+			continue
+		}
+
+		if len(settings) == 0 || !settings[len(settings)-1].list {
+			return nil, fmt.Errorf("%s:%d: unexpected indentation", name, line)
+		}
+		c := &settings[len(settings)-1]
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			c.values = append(c.values, configValue(strings.TrimPrefix(trimmed, "-")))
+			continue
+		}
+		kv := strings.SplitN(trimmed, ": ", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected - item or key: value", name, line)
+		}
+		c.values = append(c.values, configValue(kv[0])+"="+configValue(kv[1]))
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// configValue returns a YAML scalar without its quotes or trailing comment.
+func configValue(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	return s
+}
+
+// presetFlags holds the flags set by an environment variable or the
+// configuration file. Unlike the flags given on the command line, they
+// aren't reported by isFlagSet, so that a shared setting such as -format
+// doesn't conflict with the commands that don't use it.
+var presetFlags = make(map[string]bool)
+
+// presetFlag sets a flag from an environment variable or the configuration
+// file.
+func presetFlag(f *flag.Flag, value string) error {
+	presetFlags[f.Name] = true
+	return f.Value.Set(value)
+}
+
+// resetPresetFlags restores the default value of the named flags if they
+// were set by an environment variable or the configuration file.
+func resetPresetFlags(names ...string) {
+	for _, name := range names {
+		if f := flag.Lookup(name); presetFlags[name] {
+			f.Value.Set(f.DefValue)
+			delete(presetFlags, name)
+		}
+	}
+}
+
+// envPrefix is the prefix of the environment variables setting the flags,
+// such as GODEPGRAPH_MAX_NODES for -max-nodes.
+const envPrefix = "GODEPGRAPH_"
+
+// envNames holds the environment variables of the flags whose names only
+// differ in case from another flag.
+var envNames = map[string]string{
+	"T": envPrefix + "RENDER_FORMAT",
+}
+
+// envName returns the environment variable setting the named flag.
+func envName(flagName string) string {
+	if name, ok := envNames[flagName]; ok {
+		return name
+	}
+	return envPrefix + strings.ToUpper(strings.Replace(flagName, "-", "_", -1))
+}
+
+// loadEnv sets the flags that weren't given on the command line from their
+// environment variables, if set. The values of repeatable flags are
+// separated by newlines.
+func loadEnv() error {
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+	var err error
+	flag.VisitAll(func(f *flag.Flag) {
+		value, ok := os.LookupEnv(envName(f.Name))
+		if !ok || given[f.Name] || err != nil {
+			return
+		}
+		values := []string{value}
+		if _, ok := f.Value.(*rules); ok {
+			values = strings.Split(strings.TrimSpace(value), "\n")
+		}
+		for _, v := range values {
+			if serr := presetFlag(f, v); serr != nil {
+				err = fmt.Errorf("invalid value for %s: %s", envName(f.Name), serr)
+				return
+			}
+		}
+	})
+	return err
+}
+
+// loadConfig sets the flags from the named configuration file, or from the
+// one found from the current directory if name is empty. The flags given on
+// the command line, or set by environment variables, take precedence.
+func loadConfig(name string) error {
+	if name == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		if name = findConfig(cwd); name == "" {
+			return nil
+		}
+	}
+	settings, err := readConfig(name)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %s", err)
+	}
+
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+	for name := range presetFlags {
+		given[name] = true
+	}
+	for _, c := range settings {
+		f := flag.Lookup(c.name)
+		if f == nil || c.name == "config" {
+			return fmt.Errorf("%s:%d: unknown flag: %s", name, c.line, c.name)
+		}
+		if given[c.name] {
+			continue
+		}
+		values := c.values
+		// The items of repeatable flags are given one at a time, and those
+		// of the others as a comma-separated list.
+		if _, ok := f.Value.(*rules); !ok {
+			values = []string{strings.Join(values, ",")}
+		}
+		for _, v := range values {
+			if err := presetFlag(f, v); err != nil {
+				return fmt.Errorf("%s:%d: invalid value for %s: %s", name, c.line, c.name, err)
+			}
+		}
+	}
+	return nil
+}
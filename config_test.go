@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadConfig(t *testing.T) {
+	tests := []struct {
+		name, config string
+		want         []configSetting
+		err          string
+	}{
+		{
+			name: "scalars, lists and maps",
+			config: `# .godepgraph.yaml
+s: true
+max-nodes: 500 # keep it readable
+format: "json"
+p:
+  - golang.org/x/
+  - 'github.com/foo/'
+c:
+  github.com/foo: red
+r:
+  github.com/foo/bar/: ""
+deny:
+  - github.com/foo/bar/internal/...=github.com/foo/bar/cmd/...
+`,
+			want: []configSetting{
+				{name: "s", line: 2, values: []string{"true"}},
+				{name: "max-nodes", line: 3, values: []string{"500"}},
+				{name: "format", line: 4, values: []string{"json"}},
+				{name: "p", line: 5, values: []string{"golang.org/x/", "github.com/foo/"}, list: true},
+				{name: "c", line: 8, values: []string{"github.com/foo=red"}, list: true},
+				{name: "r", line: 10, values: []string{"github.com/foo/bar/="}, list: true},
+				{name: "deny", line: 12, values: []string{"github.com/foo/bar/internal/...=github.com/foo/bar/cmd/..."}, list: true},
+			},
+		},
+		{
+			name:   "missing colon",
+			config: "s true\n",
+			err:    ":1: expected flag: value",
+		},
+		{
+			name:   "item under a scalar",
+			config: "s: true\n  - x\n",
+			err:    ":2: unexpected indentation",
+		},
+		{
+			name:   "item without a dash",
+			config: "p:\n  golang.org/x/\n",
+			err:    ":2: expected - item or key: value",
+		},
+	}
+	for _, tt := range tests {
+		name := filepath.Join(t.TempDir(), configFileName)
+		if err := ioutil.WriteFile(name, []byte(tt.config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := readConfig(name)
+		switch {
+		case tt.err != "":
+			if err == nil || !strings.HasSuffix(err.Error(), tt.err) {
+				t.Errorf("%s: readConfig() error = %v, want one ending in %q", tt.name, err, tt.err)
+			}
+		case err != nil:
+			t.Errorf("%s: readConfig(): %v", tt.name, err)
+		case !reflect.DeepEqual(got, tt.want):
+			t.Errorf("%s: readConfig() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEnvNames(t *testing.T) {
+	flags := make(map[string]string)
+	flag.VisitAll(func(f *flag.Flag) {
+		name := envName(f.Name)
+		if other, ok := flags[name]; ok {
+			t.Errorf("-%s and -%s are both set by %s", other, f.Name, name)
+		}
+		flags[name] = f.Name
+	})
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// writeCSV writes the edges of the graph as CSV rows of importing package,
+// imported package and edge type.
+func writeCSV(w io.Writer, g *graph) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"from", "to", "edge_type"})
+	for _, e := range g.edges() {
+		cw.Write([]string{e.From, e.To, e.Type})
+	}
+	cw.Flush()
+	return cw.Error()
+}
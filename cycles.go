@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// sccs returns the strongly connected components of the graph that contain
+// more than one package, i.e. the sets of packages that are part of an import
+// cycle. Components and their members are in graph order.
+func (g *graph) sccs() [][]string {
+	index := make(map[string]int)
+	lowlink := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var components [][]string
+
+	var connect func(n *node)
+	connect = func(n *node) {
+		index[n.ImportPath] = len(index)
+		lowlink[n.ImportPath] = index[n.ImportPath]
+		stack = append(stack, n.ImportPath)
+		onStack[n.ImportPath] = true
+
+		for _, imp := range n.Imports {
+			if _, ok := index[imp]; !ok {
+				connect(g.node(imp))
+				if lowlink[imp] < lowlink[n.ImportPath] {
+					lowlink[n.ImportPath] = lowlink[imp]
+				}
+			} else if onStack[imp] && index[imp] < lowlink[n.ImportPath] {
+				lowlink[n.ImportPath] = index[imp]
+			}
+		}
+
+		if lowlink[n.ImportPath] == index[n.ImportPath] {
+			var component []string
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == n.ImportPath {
+					break
+				}
+			}
+			if len(component) > 1 {
+				components = append(components, component)
+			}
+		}
+	}
+
+	for _, n := range g.Nodes {
+		if _, ok := index[n.ImportPath]; !ok {
+			connect(n)
+		}
+	}
+
+	// Put everything in graph order to keep the output stable.
+	order := make(map[string]int)
+	for i, n := range g.Nodes {
+		order[n.ImportPath] = i
+	}
+	for _, c := range components {
+		sortByOrder(c, order)
+	}
+	sortComponents(components, order)
+	return components
+}
+
+// cycleSet holds the packages and imports that are part of an import cycle.
+type cycleSet struct {
+	component map[string]int
+}
+
+// cycles returns the set of packages and imports that are part of a cycle.
+func (g *graph) cycles() cycleSet {
+	cs := cycleSet{component: make(map[string]int)}
+	for i, c := range g.sccs() {
+		for _, p := range c {
+			cs.component[p] = i
+		}
+	}
+	return cs
+}
+
+// hasNode reports whether the package is part of a cycle.
+func (cs cycleSet) hasNode(importPath string) bool {
+	_, ok := cs.component[importPath]
+	return ok
+}
+
+// hasEdge reports whether the import of to by from is part of a cycle.
+func (cs cycleSet) hasEdge(from, to string) bool {
+	c, ok := cs.component[from]
+	return ok && cs.component[to] == c && cs.hasNode(to)
+}
+
+// shortestCycle returns the shortest import cycle through the first package of
+// a strongly connected component, starting and ending with that package.
+func (g *graph) shortestCycle(component []string) []string {
+	members := make(map[string]bool)
+	for _, p := range component {
+		members[p] = true
+	}
+	start := component[0]
+	prev := map[string]string{}
+	queue := []string{start}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, imp := range g.node(p).Imports {
+			if !members[imp] {
+				continue
+			}
+			if imp == start {
+				cycle := []string{start}
+				for q := p; q != start; q = prev[q] {
+					cycle = append(cycle, q)
+				}
+				cycle = append(cycle, start)
+				for i, j := 0, len(cycle)-1; i < j; i, j = i+1, j-1 {
+					cycle[i], cycle[j] = cycle[j], cycle[i]
+				}
+				return cycle
+			}
+			if _, seen := prev[imp]; !seen {
+				prev[imp] = p
+				queue = append(queue, imp)
+			}
+		}
+	}
+	return nil
+}
+
+// feedbackArcs returns a small set of imports between the packages of a
+// component whose removal breaks all of its cycles, using the greedy
+// heuristic of Eades, Lin and Smyth: the packages are ordered by repeatedly
+// taking out sinks, sources, or else the package with the most imports
+// compared to its importers, and the imports going backwards in that order
+// are returned.
+func (g *graph) feedbackArcs(component []string) [][2]string {
+	left := make(map[string]bool)
+	for _, p := range component {
+		left[p] = true
+	}
+	imports := make(map[string][]string)
+	importers := make(map[string][]string)
+	for _, p := range component {
+		for _, imp := range g.node(p).Imports {
+			if left[imp] && imp != p {
+				imports[p] = append(imports[p], imp)
+				importers[imp] = append(importers[imp], p)
+			}
+		}
+	}
+	degree := func(edges []string) int {
+		d := 0
+		for _, q := range edges {
+			if left[q] {
+				d++
+			}
+		}
+		return d
+	}
+
+	var head, tail []string
+	for len(head)+len(tail) < len(component) {
+		removed := false
+		for _, p := range component {
+			if !left[p] {
+				continue
+			}
+			if degree(imports[p]) == 0 {
+				tail = append([]string{p}, tail...)
+				left[p], removed = false, true
+			} else if degree(importers[p]) == 0 {
+				head = append(head, p)
+				left[p], removed = false, true
+			}
+		}
+		if removed {
+			continue
+		}
+		best, bestDelta := "", 0
+		for _, p := range component {
+			if !left[p] {
+				continue
+			}
+			if delta := degree(imports[p]) - degree(importers[p]); best == "" || delta > bestDelta {
+				best, bestDelta = p, delta
+			}
+		}
+		head = append(head, best)
+		left[best] = false
+	}
+
+	position := make(map[string]int)
+	for i, p := range append(head, tail...) {
+		position[p] = i
+	}
+	var arcs [][2]string
+	for _, p := range component {
+		for _, imp := range imports[p] {
+			if position[imp] < position[p] {
+				arcs = append(arcs, [2]string{p, imp})
+			}
+		}
+	}
+	return arcs
+}
+
+// reportCycles prints a summary of the import cycles in the graph.
+func reportCycles(w io.Writer, g *graph) {
+	components := g.sccs()
+	if len(components) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "found %d import cycle(s):\n", len(components))
+	for _, c := range components {
+		var names []string
+		for _, p := range g.shortestCycle(c) {
+			names = append(names, processName(p))
+		}
+		fmt.Fprintf(w, "  %d packages: %s\n", len(c), strings.Join(names, " -> "))
+	}
+
+	var arcs [][2]string
+	for _, c := range components {
+		arcs = append(arcs, g.feedbackArcs(c)...)
+	}
+	fmt.Fprintf(w, "to break them, remove %d import(s):\n", len(arcs))
+	for _, a := range arcs {
+		pos := g.node(a[0]).importPos[a[1]]
+		fmt.Fprintf(w, "  %s -> %s%s\n", processName(a[0]), processName(a[1]), posSuffix(pos))
+	}
+}
+
+// sortByOrder sorts import paths by their position in the graph.
+func sortByOrder(paths []string, order map[string]int) {
+	sort.Slice(paths, func(i, j int) bool {
+		return order[paths[i]] < order[paths[j]]
+	})
+}
+
+// sortComponents sorts components by the position of their first member.
+func sortComponents(components [][]string, order map[string]int) {
+	sort.Slice(components, func(i, j int) bool {
+		return order[components[i][0]] < order[components[j][0]]
+	})
+}
+
+// cyclesOnly returns the part of the graph made of the packages and imports
+// that are part of an import cycle.
+func (g *graph) cyclesOnly() *graph {
+	cycles := g.cycles()
+	return g.subgraph(func(n *node) bool {
+		return cycles.hasNode(n.ImportPath)
+	}, cycles.hasEdge)
+}
+
+// condense returns the graph with the packages of each import cycle merged
+// into a single node labeled with the number of packages in the cycle. The
+// result has no cycles.
+func (g *graph) condense() *graph {
+	names := make(map[string]string)
+	for _, c := range g.sccs() {
+		name := fmt.Sprintf("%s (%d packages)", c[0], len(c))
+		for _, p := range c {
+			names[p] = name
+		}
+	}
+	return g.merge(func(n *node) string {
+		return names[n.ImportPath]
+	})
+}
@@ -0,0 +1,66 @@
+package main
+
+import "testing"
+
+func TestFeedbackArcs(t *testing.T) {
+	tests := []struct {
+		name    string
+		imports map[string][]string
+		arcs    int
+	}{
+		{
+			name:    "two packages",
+			imports: map[string][]string{"a": {"b"}, "b": {"a"}},
+			arcs:    1,
+		},
+		{
+			name:    "ring",
+			imports: map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"d"}, "d": {"a"}},
+			arcs:    1,
+		},
+		{
+			name: "shared import",
+			imports: map[string][]string{
+				"a": {"b", "c"},
+				"b": {"a"},
+				"c": {"a"},
+			},
+			arcs: 2,
+		},
+		{
+			name: "two rings through one package",
+			imports: map[string][]string{
+				"hub": {"a", "c"},
+				"a":   {"b"},
+				"b":   {"hub"},
+				"c":   {"d"},
+				"d":   {"hub"},
+			},
+			arcs: 2,
+		},
+	}
+	for _, tt := range tests {
+		g := testGraph(nil, tt.imports)
+		sccs := g.sccs()
+		if len(sccs) != 1 {
+			t.Fatalf("%s: sccs() = %v, want a single component", tt.name, sccs)
+		}
+		arcs := g.feedbackArcs(sccs[0])
+		if len(arcs) != tt.arcs {
+			t.Errorf("%s: feedbackArcs() = %v, want %d imports", tt.name, arcs, tt.arcs)
+		}
+		removed := make(map[[2]string]bool)
+		for _, a := range arcs {
+			if !g.hasEdge(a[0], a[1]) {
+				t.Errorf("%s: feedbackArcs() returned %s -> %s, which isn't an import", tt.name, a[0], a[1])
+			}
+			removed[a] = true
+		}
+		g = g.subgraph(func(*node) bool { return true }, func(from, to string) bool {
+			return !removed[[2]string{from, to}]
+		})
+		if sccs := g.sccs(); len(sccs) != 0 {
+			t.Errorf("%s: cycles %v remain without the imports %v", tt.name, sccs, arcs)
+		}
+	}
+}
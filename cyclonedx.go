@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+type cdxBOM struct {
+	BOMFormat    string          `json:"bomFormat"`
+	SpecVersion  string          `json:"specVersion"`
+	Version      int             `json:"version"`
+	Metadata     cdxMetadata     `json:"metadata"`
+	Components   []cdxComponent  `json:"components"`
+	Dependencies []cdxDependency `json:"dependencies"`
+}
+
+type cdxMetadata struct {
+	Tools     []cdxComponent `json:"tools,omitempty"`
+	Component *cdxComponent  `json:"component,omitempty"`
+}
+
+type cdxComponent struct {
+	Type    string `json:"type"`
+	BOMRef  string `json:"bom-ref,omitempty"`
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	PURL    string `json:"purl,omitempty"`
+}
+
+type cdxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// writeCycloneDX writes a CycloneDX SBOM listing the modules of the packages
+// in the graph and the dependencies between them. Packages outside of a module
+// are listed under their repository root without a version, and the standard
+// library is left out.
+func writeCycloneDX(w io.Writer, g *graph) error {
+	bom := cdxBOM{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.5",
+		Version:     1,
+		Metadata: cdxMetadata{
+			Tools: []cdxComponent{{Type: "application", Name: "godepgraph"}},
+		},
+		Components:   []cdxComponent{},
+		Dependencies: []cdxDependency{},
+	}
+
+	components := make(map[string]cdxComponent)
+	deps := make(map[string]map[string]bool)
+	for _, n := range g.Nodes {
+		if n.Goroot {
+			continue
+		}
+		c := cdxComponentOf(n)
+		components[c.BOMRef] = c
+		if deps[c.BOMRef] == nil {
+			deps[c.BOMRef] = make(map[string]bool)
+		}
+		for _, imp := range n.Imports {
+			impNode := g.node(imp)
+			if impNode.Goroot {
+				continue
+			}
+			if ref := cdxComponentOf(impNode).BOMRef; ref != c.BOMRef {
+				deps[c.BOMRef][ref] = true
+			}
+		}
+	}
+
+	var mainRef string
+	if len(g.Roots) > 0 {
+		if root := g.node(g.Roots[0]); !root.Goroot {
+			c := cdxComponentOf(root)
+			c.Type = "application"
+			mainRef = c.BOMRef
+			bom.Metadata.Component = &c
+		}
+	}
+
+	var refs []string
+	for ref := range components {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+	for _, ref := range refs {
+		if ref != mainRef {
+			bom.Components = append(bom.Components, components[ref])
+		}
+		d := cdxDependency{Ref: ref, DependsOn: []string{}}
+		for dep := range deps[ref] {
+			d.DependsOn = append(d.DependsOn, dep)
+		}
+		sort.Strings(d.DependsOn)
+		bom.Dependencies = append(bom.Dependencies, d)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(bom)
+}
+
+// cdxComponentOf returns the SBOM component a package belongs to.
+func cdxComponentOf(n *node) cdxComponent {
+	name := n.Module
+	if name == "" {
+		name = repoRoot(n.ImportPath)
+	}
+	if name == "" {
+		name = n.ImportPath
+	}
+	purl := "pkg:golang/" + name
+	if n.Version != "" {
+		purl += "@" + n.Version
+	}
+	return cdxComponent{
+		Type:    "library",
+		BOMRef:  purl,
+		Name:    name,
+		Version: n.Version,
+		PURL:    purl,
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// writeCypher writes the graph as Neo4j Cypher statements. Packages are merged
+// as :Package nodes keyed by import path and imports as :IMPORTS
+// relationships, so the statements can be run repeatedly and for several
+// repositories into the same database.
+func writeCypher(w io.Writer, g *graph) error {
+	fmt.Fprintln(w, "CREATE CONSTRAINT package_import_path IF NOT EXISTS FOR (p:Package) REQUIRE p.importPath IS UNIQUE;")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(w, "MERGE (p:Package {importPath: %s}) SET p.dir = %s, p.goroot = %t, p.cgo = %t, p.vendored = %t, p.module = %s, p.version = %s;\n",
+			cypherString(n.ImportPath), cypherString(n.Dir), n.Goroot, n.Cgo, n.Vendored, cypherString(n.Module), cypherString(n.Version))
+	}
+	for _, e := range g.edges() {
+		if _, err := fmt.Fprintf(w, "MATCH (a:Package {importPath: %s}), (b:Package {importPath: %s}) MERGE (a)-[r:IMPORTS]->(b) SET r.type = %s, r.weight = %d;\n",
+			cypherString(e.From), cypherString(e.To), cypherString(e.Type), e.Weight); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var cypherReplacer = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// cypherString returns s as a single-quoted Cypher string literal.
+func cypherString(s string) string {
+	return "'" + cypherReplacer.Replace(s) + "'"
+}
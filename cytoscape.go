@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type cytoscapeElements struct {
+	Nodes []cytoscapeElement `json:"nodes"`
+	Edges []cytoscapeElement `json:"edges"`
+}
+
+type cytoscapeElement struct {
+	Data    map[string]interface{} `json:"data"`
+	Classes string                 `json:"classes,omitempty"`
+}
+
+// writeCytoscape writes the graph as Cytoscape.js elements JSON. Nodes are
+// given the stdlib, cgo and vendored style classes where they apply.
+func writeCytoscape(w io.Writer, g *graph) error {
+	elements := cytoscapeElements{
+		Nodes: []cytoscapeElement{},
+		Edges: []cytoscapeElement{},
+	}
+	for _, n := range g.Nodes {
+		var classes []string
+		if n.Goroot {
+			classes = append(classes, "stdlib")
+		}
+		if n.Cgo {
+			classes = append(classes, "cgo")
+		}
+		if n.Vendored {
+			classes = append(classes, "vendored")
+		}
+		elements.Nodes = append(elements.Nodes, cytoscapeElement{
+			Data: map[string]interface{}{
+				"id":    n.ImportPath,
+				"label": processName(n.ImportPath),
+				"color": rgbColor(nodeColor(n)),
+			},
+			Classes: strings.Join(classes, " "),
+		})
+	}
+	for i, e := range g.edges() {
+		elements.Edges = append(elements.Edges, cytoscapeElement{
+			Data: map[string]interface{}{
+				"id":     "e" + strconv.Itoa(i),
+				"source": e.From,
+				"target": e.To,
+				"weight": e.Weight,
+			},
+			Classes: e.Type,
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(elements)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// writeD2 writes the graph in Terrastruct D2 syntax. Packages that share a
+// repository root are placed in a container named after it.
+func writeD2(w io.Writer, g *graph) error {
+	if *horizontal {
+		fmt.Fprintln(w, "direction: right")
+	}
+
+	keys := make(map[string]string)
+	var roots []string
+	byRoot := make(map[string][]*node)
+	for _, n := range g.Nodes {
+		root := repoRoot(n.ImportPath)
+		if root == "" {
+			keys[n.ImportPath] = strconv.Quote(n.ImportPath)
+			continue
+		}
+		if _, ok := byRoot[root]; !ok {
+			roots = append(roots, root)
+		}
+		byRoot[root] = append(byRoot[root], n)
+		keys[n.ImportPath] = strconv.Quote(root) + "." + strconv.Quote(n.ImportPath)
+	}
+
+	for _, n := range g.Nodes {
+		if repoRoot(n.ImportPath) == "" {
+			writeD2Node(w, "", n)
+		}
+	}
+	for _, root := range roots {
+		fmt.Fprintf(w, "%s: {\n", strconv.Quote(root))
+		for _, n := range byRoot[root] {
+			writeD2Node(w, "  ", n)
+		}
+		fmt.Fprintln(w, "}")
+	}
+
+	for _, e := range g.edges() {
+		if _, err := fmt.Fprintf(w, "%s -> %s\n", keys[e.From], keys[e.To]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeD2Node(w io.Writer, indent string, n *node) {
+	fmt.Fprintf(w, "%s%s: {\n", indent, strconv.Quote(n.ImportPath))
+	fmt.Fprintf(w, "%s  label: %s\n", indent, strconv.Quote(processName(n.ImportPath)))
+	fmt.Fprintf(w, "%s  style.fill: %s\n", indent, strconv.Quote(rgbColor(nodeColor(n))))
+	fmt.Fprintf(w, "%s}\n", indent)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"html/template"
+	"io"
+)
+
+type d3Graph struct {
+	Nodes []d3Node `json:"nodes"`
+	Links []d3Link `json:"links"`
+}
+
+type d3Node struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Group string `json:"group"`
+	Color string `json:"color"`
+}
+
+type d3Link struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Value  int    `json:"value"`
+}
+
+// newD3Graph converts the graph to the nodes and links layout used by
+// d3-force and force-graph. Nodes are grouped by repository root, with the
+// standard library in its own group, and links carry the edge weight.
+func newD3Graph(g *graph) d3Graph {
+	dg := d3Graph{Nodes: []d3Node{}, Links: []d3Link{}}
+	for _, n := range g.Nodes {
+		group := repoRoot(n.ImportPath)
+		if n.Goroot {
+			group = "stdlib"
+		} else if group == "" {
+			group = n.ImportPath
+		}
+		dg.Nodes = append(dg.Nodes, d3Node{
+			ID:    n.ImportPath,
+			Name:  processName(n.ImportPath),
+			Group: group,
+			Color: rgbColor(nodeColor(n)),
+		})
+	}
+	for _, e := range g.edges() {
+		dg.Links = append(dg.Links, d3Link{Source: e.From, Target: e.To, Value: e.Weight})
+	}
+	return dg
+}
+
+// writeD3 writes the graph as d3-force / force-graph JSON data.
+func writeD3(w io.Writer, g *graph) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(newD3Graph(g))
+}
+
+// writeD3HTML writes an HTML page laying out the graph with d3-force. d3 is
+// loaded from a CDN.
+func writeD3HTML(w io.Writer, g *graph) error {
+	return d3Template.Execute(w, newD3Graph(g))
+}
+
+var d3Template = template.Must(template.New("d3").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>godepgraph</title>
+<style>
+html, body { margin: 0; height: 100%; font-family: sans-serif; font-size: 10px; }
+svg { width: 100%; height: 100%; }
+.link { stroke: #999; stroke-opacity: 0.6; }
+.node circle { stroke: #333; stroke-width: 1; }
+.node text { pointer-events: none; }
+</style>
+<script src="https://cdn.jsdelivr.net/npm/d3@7"></script>
+</head>
+<body>
+<svg></svg>
+<script>
+var graph = {{.}};
+(function() {
+	var svg = d3.select("svg");
+	var width = window.innerWidth, height = window.innerHeight;
+	var view = svg.append("g");
+	svg.call(d3.zoom().on("zoom", function(ev) { view.attr("transform", ev.transform); }));
+
+	var simulation = d3.forceSimulation(graph.nodes)
+		.force("link", d3.forceLink(graph.links).id(function(n) { return n.id; })
+			.strength(function(l) { return Math.min(1, 0.2 * l.value); }))
+		.force("charge", d3.forceManyBody().strength(-60))
+		.force("center", d3.forceCenter(width / 2, height / 2))
+		.force("collide", d3.forceCollide(8));
+
+	var link = view.append("g").selectAll("line").data(graph.links).join("line")
+		.attr("class", "link")
+		.attr("stroke-width", function(l) { return Math.sqrt(l.value); });
+
+	var node = view.append("g").selectAll("g").data(graph.nodes).join("g")
+		.attr("class", "node")
+		.call(d3.drag()
+			.on("start", function(ev, n) { if (!ev.active) { simulation.alphaTarget(0.3).restart(); } n.fx = n.x; n.fy = n.y; })
+			.on("drag", function(ev, n) { n.fx = ev.x; n.fy = ev.y; })
+			.on("end", function(ev, n) { if (!ev.active) { simulation.alphaTarget(0); } n.fx = null; n.fy = null; }));
+	node.append("circle").attr("r", 6).attr("fill", function(n) { return n.color; });
+	node.append("text").attr("x", 8).attr("y", 3).text(function(n) { return n.name; });
+	node.append("title").text(function(n) { return n.id + " (" + n.group + ")"; });
+
+	simulation.on("tick", function() {
+		link.attr("x1", function(l) { return l.source.x; }).attr("y1", function(l) { return l.source.y; })
+			.attr("x2", function(l) { return l.target.x; }).attr("y2", function(l) { return l.target.y; });
+		node.attr("transform", function(n) { return "translate(" + n.x + "," + n.y + ")"; });
+	});
+})();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/build"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// graphDiff lists the packages and imports that differ between two graphs.
+type graphDiff struct {
+	AddedNodes   []string `json:"addedNodes"`
+	RemovedNodes []string `json:"removedNodes"`
+	AddedEdges   []edge   `json:"addedEdges"`
+	RemovedEdges []edge   `json:"removedEdges"`
+}
+
+// diffGraphs compares two graphs by import paths, ignoring changes in package
+// attributes and edge types and weights.
+func diffGraphs(old, new *graph) graphDiff {
+	d := graphDiff{
+		AddedNodes:   []string{},
+		RemovedNodes: []string{},
+		AddedEdges:   []edge{},
+		RemovedEdges: []edge{},
+	}
+	for _, n := range new.Nodes {
+		if old.node(n.ImportPath) == nil {
+			d.AddedNodes = append(d.AddedNodes, n.ImportPath)
+		}
+	}
+	for _, n := range old.Nodes {
+		if new.node(n.ImportPath) == nil {
+			d.RemovedNodes = append(d.RemovedNodes, n.ImportPath)
+		}
+	}
+	for _, e := range new.edges() {
+		if !old.hasEdge(e.From, e.To) {
+			d.AddedEdges = append(d.AddedEdges, e)
+		}
+	}
+	for _, e := range old.edges() {
+		if !new.hasEdge(e.From, e.To) {
+			d.RemovedEdges = append(d.RemovedEdges, e)
+		}
+	}
+	return d
+}
+
+// empty reports whether the two graphs have the same packages and imports.
+func (d graphDiff) empty() bool {
+	return len(d.AddedNodes)+len(d.RemovedNodes)+len(d.AddedEdges)+len(d.RemovedEdges) == 0
+}
+
+// hasEdge reports whether from imports to in the graph.
+func (g *graph) hasEdge(from, to string) bool {
+	n := g.node(from)
+	return n != nil && contains(n.Imports, to)
+}
+
+// writeDiff writes the differences between two graphs as text, or in the dot
+// or json formats if one of them was requested with -format. It reports
+// whether the graphs differ.
+func writeDiff(w io.Writer, old, new *graph) (bool, error) {
+	d := diffGraphs(old, new)
+	return !d.empty(), writeGraphDiff(w, old, new, d)
+}
+
+func writeGraphDiff(w io.Writer, old, new *graph, d graphDiff) error {
+	switch {
+	case !isFlagSet("format"):
+		for _, p := range d.AddedNodes {
+			fmt.Fprintf(w, "+ %s\n", processName(p))
+		}
+		for _, p := range d.RemovedNodes {
+			fmt.Fprintf(w, "- %s\n", processName(p))
+		}
+		for _, e := range d.AddedEdges {
+			fmt.Fprintf(w, "+ %s -> %s\n", processName(e.From), processName(e.To))
+		}
+		for _, e := range d.RemovedEdges {
+			fmt.Fprintf(w, "- %s -> %s\n", processName(e.From), processName(e.To))
+		}
+		return nil
+	case *outputFormat == "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(d)
+	default:
+		return writeDiffDot(w, old, new, d)
+	}
+}
+
+// writeDiffDot writes the union of two graphs in dot format, with the added
+// packages and imports in green and the removed ones in red.
+func writeDiffDot(w io.Writer, old, new *graph, d graphDiff) error {
+	added := make(map[string]bool)
+	for _, p := range d.AddedNodes {
+		added[p] = true
+	}
+	removed := make(map[string]bool)
+	for _, p := range d.RemovedNodes {
+		removed[p] = true
+	}
+
+	fmt.Fprintln(w, "digraph godep {")
+	if *horizontal {
+		fmt.Fprintln(w, `rankdir="LR"`)
+	}
+	union := append(append([]*node(nil), new.Nodes...), old.Nodes...)
+	seen := make(map[string]bool)
+	for _, n := range union {
+		if seen[n.ImportPath] {
+			continue
+		}
+		seen[n.ImportPath] = true
+
+		attrs := []string{dotAttr("label", processName(n.ImportPath))}
+		switch {
+		case added[n.ImportPath]:
+			attrs = append(attrs, dotAttr("style", "filled"), dotAttr("fillcolor", nodeColor(n)), dotAttr("color", "green"), dotAttr("penwidth", "3"))
+		case removed[n.ImportPath]:
+			attrs = append(attrs, dotAttr("style", "filled,dashed"), dotAttr("fillcolor", nodeColor(n)), dotAttr("color", "red"), dotAttr("penwidth", "3"))
+		default:
+			attrs = append(attrs, dotAttr("style", "filled"), dotAttr("color", nodeColor(n)))
+		}
+		fmt.Fprintf(w, "_%d [%s];\n", getId(n.ImportPath), strings.Join(attrs, " "))
+	}
+	for _, e := range new.edges() {
+		if old.hasEdge(e.From, e.To) {
+			fmt.Fprintf(w, "_%d -> _%d;\n", getId(e.From), getId(e.To))
+		} else {
+			fmt.Fprintf(w, "_%d -> _%d [color=\"green\" penwidth=\"2\"];\n", getId(e.From), getId(e.To))
+		}
+	}
+	for _, e := range d.RemovedEdges {
+		fmt.Fprintf(w, "_%d -> _%d [color=\"red\" style=\"dashed\"];\n", getId(e.From), getId(e.To))
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+// runDiff scans the packages at two git revisions of the repository
+// containing dir and writes the differences between the two graphs. Each
+// revision is checked out in a temporary worktree, and the packages are
+// resolved relative to the same subdirectory of the worktree as dir is of
+// the repository, so they should be given as relative paths in module mode.
+// It reports whether the graphs differ.
+func runDiff(w io.Writer, dir, oldRef, newRef string, args []string) (bool, error) {
+	top, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return false, err
+	}
+	prefix, err := git(dir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return false, err
+	}
+
+	var graphs []*graph
+	for _, ref := range []string{oldRef, newRef} {
+		g, err := scanRevision(top, prefix, ref, args)
+		if err != nil {
+			return false, err
+		}
+		graphs = append(graphs, g)
+	}
+	return writeDiff(w, graphs[0], graphs[1])
+}
+
+// diffSnapshots writes the differences between two graphs saved with the
+// json output format, and reports whether they differ.
+func diffSnapshots(w io.Writer, oldFile, newFile string) (bool, error) {
+	old, err := readJSON(oldFile)
+	if err != nil {
+		return false, err
+	}
+	new, err := readJSON(newFile)
+	if err != nil {
+		return false, err
+	}
+	return writeDiff(w, old, new)
+}
+
+// diffBuilds scans the packages with the build context given on the command
+// line, then again after applying configure to it, and writes the
+// differences between the two graphs. It reports whether the graphs differ.
+func diffBuilds(w io.Writer, dir string, args []string, configure func(ctx *build.Context)) (bool, error) {
+	base := buildContext
+	defer func() {
+		buildContext = base
+	}()
+
+	var graphs []*graph
+	for i := 0; i < 2; i++ {
+		if i == 1 {
+			configure(&buildContext)
+		}
+		g, err := scan(dir, args)
+		if err != nil {
+			return false, err
+		}
+		if g, err = transform(g); err != nil {
+			return false, err
+		}
+		graphs = append(graphs, g)
+	}
+	return writeDiff(w, graphs[0], graphs[1])
+}
+
+// parsePlatform parses a platform given as goos/goarch, or as goos alone to
+// keep the architecture of ctx.
+func parsePlatform(ctx *build.Context, platform string) (goos, goarch string, err error) {
+	parts := strings.Split(platform, "/")
+	if len(parts) > 2 || parts[0] == "" || (len(parts) == 2 && parts[1] == "") {
+		return "", "", fmt.Errorf("expected goos/goarch, got %q", platform)
+	}
+	if len(parts) == 1 {
+		return parts[0], ctx.GOARCH, nil
+	}
+	return parts[0], parts[1], nil
+}
+
+// setPlatform makes ctx build for the given platform. Like the go command,
+// cgo is disabled when building for a platform other than the host's.
+func setPlatform(ctx *build.Context, goos, goarch string) {
+	ctx.GOOS, ctx.GOARCH = goos, goarch
+	ctx.CgoEnabled = build.Default.CgoEnabled && goos == runtime.GOOS && goarch == runtime.GOARCH
+}
+
+// scanRevision checks out a git revision in a temporary worktree and scans
+// the packages there.
+func scanRevision(top, prefix, ref string, args []string) (*graph, error) {
+	tmp, err := ioutil.TempDir("", "godepgraph")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	worktree := filepath.Join(tmp, "src")
+	if _, err := git(top, "worktree", "add", "--detach", worktree, ref); err != nil {
+		return nil, err
+	}
+	defer git(top, "worktree", "remove", "--force", worktree)
+
+	g, err := scan(filepath.Join(worktree, filepath.FromSlash(prefix)), args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", ref, err)
+	}
+	return transform(g)
+}
+
+// git runs a git command in dir and returns its trimmed output.
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %s", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// dominators returns the immediate dominator of each package reachable from
+// the root packages: the closest package that every import chain from the
+// roots to it goes through. The root packages, and the packages that have no
+// such dominator, map to the empty string.
+func (g *graph) dominators() map[string]string {
+	// Number the packages in reverse postorder from a virtual root importing
+	// the root packages, which gets index 0.
+	var order []string
+	index := map[string]int{"": 0}
+	seen := make(map[string]bool)
+	var visit func(p string)
+	visit = func(p string) {
+		seen[p] = true
+		for _, imp := range g.node(p).Imports {
+			if !seen[imp] {
+				visit(imp)
+			}
+		}
+		order = append(order, p)
+	}
+	for _, root := range g.Roots {
+		if !seen[root] {
+			visit(root)
+		}
+	}
+	for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+		order[i], order[j] = order[j], order[i]
+	}
+	for i, p := range order {
+		index[p] = i + 1
+	}
+
+	preds := make(map[string][]string)
+	for _, root := range g.Roots {
+		preds[root] = append(preds[root], "")
+	}
+	for _, p := range order {
+		for _, imp := range g.node(p).Imports {
+			preds[imp] = append(preds[imp], p)
+		}
+	}
+
+	// Cooper, Harvey and Kennedy's iterative algorithm.
+	idom := map[string]string{"": ""}
+	intersect := func(a, b string) string {
+		for a != b {
+			for index[a] > index[b] {
+				a = idom[a]
+			}
+			for index[b] > index[a] {
+				b = idom[b]
+			}
+		}
+		return a
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, p := range order {
+			dom, ok := "", false
+			for _, pred := range preds[p] {
+				if _, done := idom[pred]; !done {
+					continue
+				}
+				if !ok {
+					dom, ok = pred, true
+				} else {
+					dom = intersect(pred, dom)
+				}
+			}
+			if old, done := idom[p]; !done || old != dom {
+				idom[p] = dom
+				changed = true
+			}
+		}
+	}
+	delete(idom, "")
+	return idom
+}
+
+// writeDominators writes the dominator tree of the graph, where each package
+// is followed by the number of packages that are only imported through it
+// and would therefore go away with it. Packages other than the roots that
+// don't gate any other package are left out.
+func writeDominators(w io.Writer, g *graph) error {
+	children := make(map[string][]string)
+	for p, dom := range g.dominators() {
+		children[dom] = append(children[dom], p)
+	}
+	size := make(map[string]int)
+	var count func(p string) int
+	count = func(p string) int {
+		n := 0
+		for _, c := range children[p] {
+			n += 1 + count(c)
+		}
+		size[p] = n
+		return n
+	}
+	count("")
+	for _, c := range children {
+		sort.Slice(c, func(i, j int) bool {
+			if size[c[i]] != size[c[j]] {
+				return size[c[i]] > size[c[j]]
+			}
+			return c[i] < c[j]
+		})
+	}
+
+	var err error
+	var walk func(p string, depth int)
+	walk = func(p string, depth int) {
+		for _, c := range children[p] {
+			if size[c] == 0 && !contains(g.Roots, c) {
+				continue
+			}
+			if _, werr := fmt.Fprintf(w, "%*s%s (%d)\n", 2*depth, "", processName(c), size[c]); werr != nil && err == nil {
+				err = werr
+			}
+			walk(c, depth+1)
+		}
+	}
+	walk("", 0)
+	return err
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDominators(t *testing.T) {
+	tests := []struct {
+		name    string
+		roots   []string
+		imports map[string][]string
+		want    map[string]string
+	}{
+		{
+			name:  "diamond",
+			roots: []string{"a"},
+			imports: map[string][]string{
+				"a": {"b", "c"},
+				"b": {"d"},
+				"c": {"d"},
+				"d": {"e"},
+			},
+			want: map[string]string{"a": "", "b": "a", "c": "a", "d": "a", "e": "d"},
+		},
+		{
+			name:  "shared by two roots",
+			roots: []string{"r1", "r2"},
+			imports: map[string][]string{
+				"r1": {"x"},
+				"r2": {"x"},
+				"x":  {"y"},
+			},
+			want: map[string]string{"r1": "", "r2": "", "x": "", "y": "x"},
+		},
+		{
+			name:  "cycle",
+			roots: []string{"a"},
+			imports: map[string][]string{
+				"a": {"b"},
+				"b": {"c"},
+				"c": {"b", "d"},
+			},
+			want: map[string]string{"a": "", "b": "a", "c": "b", "d": "c"},
+		},
+		{
+			name:  "unreachable",
+			roots: []string{"a"},
+			imports: map[string][]string{
+				"a": {"b"},
+				"z": {"b"},
+			},
+			want: map[string]string{"a": "", "b": "a"},
+		},
+	}
+	for _, tt := range tests {
+		g := testGraph(tt.roots, tt.imports)
+		if got := g.dominators(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: dominators() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// duplicatedModules holds the modules that are part of a duplicate group,
+// when -duplicates is given.
+var duplicatedModules map[string]bool
+
+// duplicate is a set of modules of the graph that are likely the same
+// project.
+type duplicate struct {
+	// Name is the module path without its major version suffix, or the name
+	// of the repository for forks.
+	Name    string
+	Forks   bool
+	Modules []string
+}
+
+var (
+	majorSuffix = regexp.MustCompile(`/v[0-9]+$`)
+	gopkgSuffix = regexp.MustCompile(`^(gopkg\.in/.*)\.v[0-9]+$`)
+)
+
+// withoutMajor returns a module path without its major version suffix, such
+// as /v2, or .v2 for gopkg.in.
+func withoutMajor(path string) string {
+	if m := gopkgSuffix.FindStringSubmatch(path); m != nil {
+		return m[1]
+	}
+	return majorSuffix.ReplaceAllString(path, "")
+}
+
+// duplicates returns the groups of modules that appear under several major
+// versions, or several versions in vendored trees, followed by the groups of
+// modules hosted under different owners with the same repository name,
+// which are likely forks of each other.
+func (g *graph) duplicates() []duplicate {
+	versions := make(map[string]map[string]bool)
+	forks := make(map[string]map[string]bool)
+	for _, n := range g.Nodes {
+		if n.Module == "" {
+			continue
+		}
+		mod := n.Module
+		if n.Version != "" {
+			mod += "@" + n.Version
+		}
+		base := withoutMajor(n.Module)
+		if versions[base] == nil {
+			versions[base] = make(map[string]bool)
+		}
+		versions[base][mod] = true
+
+		if root := repoRoot(base); root != "" && root == base && strings.Count(root, "/") == 2 {
+			name := root[:strings.Index(root, "/")] + "/*/" + root[strings.LastIndex(root, "/")+1:]
+			if forks[name] == nil {
+				forks[name] = make(map[string]bool)
+			}
+			forks[name][base] = true
+		}
+	}
+
+	var dups []duplicate
+	for _, group := range []struct {
+		modules map[string]map[string]bool
+		forks   bool
+	}{{versions, false}, {forks, true}} {
+		var names []string
+		for name, mods := range group.modules {
+			if len(mods) > 1 {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			d := duplicate{Name: name, Forks: group.forks}
+			for mod := range group.modules[name] {
+				d.Modules = append(d.Modules, mod)
+			}
+			sort.Strings(d.Modules)
+			dups = append(dups, d)
+		}
+	}
+	return dups
+}
+
+// duplicatedModuleSet returns the paths of the modules that are part of one of
+// the duplicate groups.
+func duplicatedModuleSet(dups []duplicate) map[string]bool {
+	set := make(map[string]bool)
+	for _, d := range dups {
+		for _, mod := range d.Modules {
+			if at := strings.LastIndex(mod, "@"); at >= 0 {
+				mod = mod[:at]
+			}
+			set[mod] = true
+		}
+	}
+	return set
+}
+
+// reportDuplicates writes a summary of the duplicated modules.
+func reportDuplicates(w io.Writer, dups []duplicate) {
+	if len(dups) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "found %d duplicated module(s):\n", len(dups))
+	for _, d := range dups {
+		kind := "versions"
+		if d.Forks {
+			kind = "forks"
+		}
+		fmt.Fprintf(w, "  %s (%s): %s\n", d.Name, kind, strings.Join(d.Modules, ", "))
+	}
+}
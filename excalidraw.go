@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+type excalidrawScene struct {
+	Type     string              `json:"type"`
+	Version  int                 `json:"version"`
+	Source   string              `json:"source"`
+	Elements []excalidrawElement `json:"elements"`
+	AppState map[string]string   `json:"appState"`
+}
+
+type excalidrawElement struct {
+	ID              string             `json:"id"`
+	Type            string             `json:"type"`
+	X               float64            `json:"x"`
+	Y               float64            `json:"y"`
+	Width           float64            `json:"width"`
+	Height          float64            `json:"height"`
+	StrokeColor     string             `json:"strokeColor"`
+	BackgroundColor string             `json:"backgroundColor"`
+	FillStyle       string             `json:"fillStyle"`
+	StrokeWidth     int                `json:"strokeWidth"`
+	Roughness       int                `json:"roughness"`
+	Opacity         int                `json:"opacity"`
+	Seed            int                `json:"seed"`
+	Version         int                `json:"version"`
+	BoundElements   []excalidrawBound  `json:"boundElements"`
+	Text            string             `json:"text,omitempty"`
+	OriginalText    string             `json:"originalText,omitempty"`
+	FontSize        int                `json:"fontSize,omitempty"`
+	FontFamily      int                `json:"fontFamily,omitempty"`
+	TextAlign       string             `json:"textAlign,omitempty"`
+	VerticalAlign   string             `json:"verticalAlign,omitempty"`
+	ContainerID     string             `json:"containerId,omitempty"`
+	Points          [][2]float64       `json:"points,omitempty"`
+	StartBinding    *excalidrawBinding `json:"startBinding,omitempty"`
+	EndBinding      *excalidrawBinding `json:"endBinding,omitempty"`
+	EndArrowhead    string             `json:"endArrowhead,omitempty"`
+}
+
+type excalidrawBound struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+type excalidrawBinding struct {
+	ElementID string  `json:"elementId"`
+	Focus     float64 `json:"focus"`
+	Gap       float64 `json:"gap"`
+}
+
+// writeExcalidraw writes the graph as an Excalidraw scene, with the packages
+// laid out as boxes in layers and the imports as arrows bound to them, so the
+// diagram can be rearranged and annotated.
+func writeExcalidraw(w io.Writer, g *graph) error {
+	const boxHeight, fontSize = 40, 16
+
+	scene := excalidrawScene{
+		Type:     "excalidraw",
+		Version:  2,
+		Source:   "godepgraph",
+		Elements: []excalidrawElement{},
+		AppState: map[string]string{"viewBackgroundColor": "#ffffff"},
+	}
+
+	boxes := make(map[string]int)
+	for d, layer := range g.layers() {
+		x := 0.0
+		for _, n := range layer {
+			label := processName(n.ImportPath)
+			width := float64(len(label)*fontSize)*0.6 + 20
+			id := fmt.Sprintf("pkg%d", getId(n.ImportPath))
+			textID := id + "-text"
+			bx, by := x, float64(d)*120
+			if *horizontal {
+				bx, by = by*3, x/4
+			}
+			// Excalidraw doesn't know the Graphviz color names.
+			color := rgbColor(nodeColor(n))
+
+			boxes[n.ImportPath] = len(scene.Elements)
+			scene.Elements = append(scene.Elements, excalidrawElement{
+				ID: id, Type: "rectangle",
+				X: bx, Y: by, Width: width, Height: boxHeight,
+				StrokeColor: "#1e1e1e", BackgroundColor: color, FillStyle: "solid",
+				StrokeWidth: 1, Roughness: 1, Opacity: 100, Seed: getId(n.ImportPath) + 1, Version: 1,
+				BoundElements: []excalidrawBound{{ID: textID, Type: "text"}},
+			}, excalidrawElement{
+				ID: textID, Type: "text",
+				X: bx + 10, Y: by + (boxHeight-fontSize*1.25)/2, Width: width - 20, Height: fontSize * 1.25,
+				StrokeColor: "#1e1e1e", BackgroundColor: "transparent", FillStyle: "solid",
+				StrokeWidth: 1, Roughness: 1, Opacity: 100, Seed: getId(n.ImportPath) + 1, Version: 1,
+				BoundElements: []excalidrawBound{},
+				Text:          label, OriginalText: label,
+				FontSize: fontSize, FontFamily: 3, TextAlign: "center", VerticalAlign: "middle",
+				ContainerID: id,
+			})
+			x += width + 40
+		}
+	}
+
+	for i, e := range g.edges() {
+		from, to := &scene.Elements[boxes[e.From]], &scene.Elements[boxes[e.To]]
+		id := fmt.Sprintf("imp%d", i)
+		x1, y1 := from.X+from.Width/2, from.Y+from.Height
+		x2, y2 := to.X+to.Width/2, to.Y
+		from.BoundElements = append(from.BoundElements, excalidrawBound{ID: id, Type: "arrow"})
+		to.BoundElements = append(to.BoundElements, excalidrawBound{ID: id, Type: "arrow"})
+		scene.Elements = append(scene.Elements, excalidrawElement{
+			ID: id, Type: "arrow",
+			X: x1, Y: y1, Width: x2 - x1, Height: y2 - y1,
+			StrokeColor: "#1e1e1e", BackgroundColor: "transparent", FillStyle: "solid",
+			StrokeWidth: 1, Roughness: 0, Opacity: 100, Seed: i + 1, Version: 1,
+			BoundElements: []excalidrawBound{},
+			Points:        [][2]float64{{0, 0}, {x2 - x1, y2 - y1}},
+			StartBinding:  &excalidrawBinding{ElementID: from.ID, Gap: 1},
+			EndBinding:    &excalidrawBinding{ElementID: to.ID, Gap: 1},
+			EndArrowhead:  "arrow",
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(scene)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+const exploreHelp = `commands:
+  <number>   go to the package with this number
+  <name>     go to the package with this import path, or list those containing it
+  b          go back to the previous package
+  r          list the root packages
+  q          quit
+`
+
+// explore lets the user browse the graph from a terminal, reading commands
+// from r and writing to w. Each package is shown with its imports and its
+// importers side by side, numbered so that they can be jumped to.
+func explore(r io.Reader, w io.Writer, g *graph) error {
+	importers := g.importers()
+	var history []string
+	// choices holds the packages listed last, by number.
+	var choices []string
+
+	list := func(title string, paths []string) error {
+		choices = paths
+		fmt.Fprintf(w, "%s:\n", title)
+		for i, p := range paths {
+			fmt.Fprintf(w, "%4d %s\n", i+1, processName(p))
+		}
+		return nil
+	}
+	show := func(p string) error {
+		n := g.node(p)
+		imports := append([]string(nil), n.Imports...)
+		sort.Strings(imports)
+		importedBy := importers[p]
+		sort.Strings(importedBy)
+		choices = append(imports, importedBy...)
+
+		fmt.Fprintf(w, "\n%s", processName(p))
+		if n.Module != "" {
+			fmt.Fprintf(w, " (%s)", n.Module)
+		}
+		fmt.Fprintf(w, "\n%d import(s), %d importer(s)\n\n", len(imports), len(importedBy))
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "IMPORTS\tIMPORTED BY")
+		for i := 0; i < len(imports) || i < len(importedBy); i++ {
+			var left, right string
+			if i < len(imports) {
+				left = fmt.Sprintf("%4d %s", i+1, processName(imports[i]))
+			}
+			if i < len(importedBy) {
+				right = fmt.Sprintf("%4d %s", len(imports)+i+1, processName(importedBy[i]))
+			}
+			if right == "" {
+				// Once there are no more importers, don't pad the imports.
+				fmt.Fprintln(tw, left)
+				continue
+			}
+			fmt.Fprintf(tw, "%s\t%s\n", left, right)
+		}
+		return tw.Flush()
+	}
+	visit := func(p string) error {
+		history = append(history, p)
+		return show(p)
+	}
+
+	if len(g.Roots) == 1 {
+		if err := visit(g.Roots[0]); err != nil {
+			return err
+		}
+	} else {
+		list("root packages", g.Roots)
+	}
+
+	in := bufio.NewScanner(r)
+	for {
+		fmt.Fprint(w, "> ")
+		if !in.Scan() {
+			fmt.Fprintln(w)
+			return in.Err()
+		}
+		cmd := strings.TrimSpace(in.Text())
+		var err error
+		switch {
+		case cmd == "":
+		case cmd == "q":
+			return nil
+		case cmd == "?" || cmd == "h":
+			fmt.Fprint(w, exploreHelp)
+		case cmd == "r":
+			err = list("root packages", g.Roots)
+		case cmd == "b":
+			if len(history) < 2 {
+				fmt.Fprintln(w, "no previous package")
+				break
+			}
+			history = history[:len(history)-1]
+			err = show(history[len(history)-1])
+		default:
+			if i, convErr := strconv.Atoi(cmd); convErr == nil {
+				if i < 1 || i > len(choices) {
+					fmt.Fprintf(w, "no package numbered %d\n", i)
+					break
+				}
+				err = visit(choices[i-1])
+				break
+			}
+			if g.node(cmd) != nil {
+				err = visit(cmd)
+				break
+			}
+			var matches []string
+			for _, n := range g.Nodes {
+				if strings.Contains(n.ImportPath, cmd) {
+					matches = append(matches, n.ImportPath)
+				}
+			}
+			switch len(matches) {
+			case 0:
+				fmt.Fprintf(w, "no package matches %s, type ? for help\n", cmd)
+			case 1:
+				err = visit(matches[0])
+			default:
+				err = list("matching packages", matches)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
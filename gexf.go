@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+)
+
+type gexf struct {
+	XMLName xml.Name  `xml:"gexf"`
+	Xmlns   string    `xml:"xmlns,attr"`
+	Version string    `xml:"version,attr"`
+	Graph   gexfGraph `xml:"graph"`
+}
+
+type gexfGraph struct {
+	DefaultEdgeType string         `xml:"defaultedgetype,attr"`
+	Attributes      gexfAttributes `xml:"attributes"`
+	Nodes           []gexfNode     `xml:"nodes>node"`
+	Edges           []gexfEdge     `xml:"edges>edge"`
+}
+
+type gexfAttributes struct {
+	Class      string          `xml:"class,attr"`
+	Attributes []gexfAttribute `xml:"attribute"`
+}
+
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfNode struct {
+	ID        string         `xml:"id,attr"`
+	Label     string         `xml:"label,attr"`
+	AttValues []gexfAttValue `xml:"attvalues>attvalue"`
+}
+
+type gexfAttValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type gexfEdge struct {
+	ID     string `xml:"id,attr"`
+	Source string `xml:"source,attr"`
+	Target string `xml:"target,attr"`
+	Weight int    `xml:"weight,attr"`
+	Label  string `xml:"label,attr"`
+}
+
+// writeGEXF writes the graph as a GEXF document for Gephi. Nodes carry the
+// stdlib, cgo and vendored attributes, and edges are weighted by the number
+// of importing files.
+func writeGEXF(w io.Writer, g *graph) error {
+	doc := gexf{
+		Xmlns:   "http://gexf.net/1.3",
+		Version: "1.3",
+		Graph: gexfGraph{
+			DefaultEdgeType: "directed",
+			Attributes: gexfAttributes{
+				Class: "node",
+				Attributes: []gexfAttribute{
+					{ID: "stdlib", Title: "stdlib", Type: "boolean"},
+					{ID: "cgo", Title: "cgo", Type: "boolean"},
+					{ID: "vendored", Title: "vendored", Type: "boolean"},
+				},
+			},
+		},
+	}
+	for _, n := range g.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, gexfNode{
+			ID:    n.ImportPath,
+			Label: processName(n.ImportPath),
+			AttValues: []gexfAttValue{
+				{For: "stdlib", Value: strconv.FormatBool(n.Goroot)},
+				{For: "cgo", Value: strconv.FormatBool(n.Cgo)},
+				{For: "vendored", Value: strconv.FormatBool(n.Vendored)},
+			},
+		})
+	}
+	for i, e := range g.edges() {
+		doc.Graph.Edges = append(doc.Graph.Edges, gexfEdge{
+			ID:     strconv.Itoa(i),
+			Source: e.From,
+			Target: e.To,
+			Weight: e.Weight,
+			Label:  e.Type,
+		})
+	}
+
+	return encodeXML(w, doc)
+}
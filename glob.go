@@ -0,0 +1,50 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// globRegexp returns a regular expression matching the import paths matched
+// by a glob, where * matches any part of a path element, ? a single
+// character other than /, and ** any number of path elements, including
+// none, so that "github.com/*/legacy/**" matches github.com/foo/legacy and
+// all the packages under it.
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	var re strings.Builder
+	re.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case i == 0 && strings.HasPrefix(glob, "**/"):
+			re.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**/"):
+			re.WriteString("(/.*)?/")
+			i += 3
+		case glob[i:] == "/**":
+			re.WriteString("(/.*)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	re.WriteString("$")
+	return regexp.Compile(re.String())
+}
+
+// matchGlobs reports whether an import path matches one of the globs.
+func matchGlobs(path string, globs []*regexp.Regexp) bool {
+	for _, g := range globs {
+		if g.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
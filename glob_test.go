@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestGlobRegexp(t *testing.T) {
+	tests := []struct {
+		glob, path string
+		want       bool
+	}{
+		{"github.com/*/legacy/**", "github.com/foo/legacy", true},
+		{"github.com/*/legacy/**", "github.com/foo/legacy/a/b", true},
+		{"github.com/*/legacy/**", "github.com/foo/bar/legacy", false},
+		{"github.com/*/legacy/**", "github.com/foo/legacyx", false},
+		{"**/internal/**", "internal", true},
+		{"**/internal/**", "github.com/foo/internal/x", true},
+		{"**/internal/**", "github.com/foo/internals", false},
+		{"a/**/b", "a/b", true},
+		{"a/**/b", "a/x/y/b", true},
+		{"a/**/b", "a/xb", false},
+		{"a/**", "a", true},
+		{"a/**", "ab", false},
+		{"**", "a/b/c", true},
+		{"foo?", "foo1", true},
+		{"foo?", "foo/", false},
+		{"*", "a/b", false},
+		{"golang.org/x/*", "golang.org/x/net", true},
+		{"golang.org/x/*", "golangXorg/x/net", false},
+	}
+	for _, tt := range tests {
+		re, err := globRegexp(tt.glob)
+		if err != nil {
+			t.Errorf("globRegexp(%q): %v", tt.glob, err)
+			continue
+		}
+		if got := re.MatchString(tt.path); got != tt.want {
+			t.Errorf("glob %q matching %q = %v, want %v", tt.glob, tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/build"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// listPackage is the part of a package listed by go list -json that is used
+// to build the graph.
+type listPackage struct {
+	ImportPath   string
+	Dir          string
+	Name         string
+	Goroot       bool
+	GoFiles      []string
+	CgoFiles     []string
+	Imports      []string
+	TestImports  []string
+	XTestImports []string
+	// ImportMap maps the imports as written in the source files to the
+	// import paths they resolve to, when they differ, such as for vendored
+	// packages.
+	ImportMap map[string]string
+	ForTest   string
+	Error     *struct {
+		Err string
+	}
+}
+
+// listedPackages holds the packages listed by go list with -golist, by
+// import path, or nil.
+var listedPackages map[string]*listPackage
+
+// listedDirs holds the packages listed by go list with -golist, by
+// directory, to resolve the imports of a package with its import map.
+var listedDirs map[string]*listPackage
+
+// listPackages resolves the packages of the targets and all their
+// dependencies with a single go list -deps -json invocation per directory,
+// which is much faster than importing each package with go/build.
+func listPackages(targets []target) error {
+	listedPackages = make(map[string]*listPackage)
+	listedDirs = make(map[string]*listPackage)
+	var dirs []string
+	paths := make(map[string][]string)
+	for _, t := range targets {
+		if paths[t.dir] == nil {
+			dirs = append(dirs, t.dir)
+		}
+		paths[t.dir] = append(paths[t.dir], t.importPath)
+	}
+	for _, dir := range dirs {
+		if err := goList(dir, paths[dir]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// goList runs go list -deps -json in dir for the given packages, with the
+// build configuration of buildContext, and records the listed packages.
+func goList(dir string, paths []string) error {
+	args := []string{"list", "-deps", "-json"}
+	if *includeTests {
+		args = append(args, "-test")
+	}
+	if *keepGoing {
+		args = append(args, "-e")
+	}
+	if len(buildContext.BuildTags) > 0 {
+		args = append(args, "-tags", strings.Join(buildContext.BuildTags, ","))
+	}
+	cmd := exec.Command("go", append(append(args, "--"), paths...)...)
+	cmd.Dir = dir
+	cgo := "0"
+	if buildContext.CgoEnabled {
+		cgo = "1"
+	}
+	cmd.Env = append(os.Environ(), "GOOS="+buildContext.GOOS, "GOARCH="+buildContext.GOARCH, "CGO_ENABLED="+cgo)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("go list failed: %s", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var p listPackage
+		if err := dec.Decode(&p); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to read go list output: %s", err)
+		}
+		// With -test, go list also lists the test variants of the
+		// packages, whose imports are already those of the packages.
+		if p.ForTest != "" || strings.HasSuffix(p.ImportPath, ".test") {
+			continue
+		}
+		if p.Error != nil {
+			if *keepGoing {
+				// Leave it to go/build, to show it as an error node.
+				continue
+			}
+			return fmt.Errorf("failed to list %s: %s", p.ImportPath, p.Error.Err)
+		}
+		listedPackages[p.ImportPath] = &p
+		listedDirs[p.Dir] = &p
+	}
+	return nil
+}
+
+// lookupListed returns the listed package that path resolves to when
+// imported from srcDir.
+func lookupListed(path, srcDir string) (*build.Package, bool) {
+	if importer := listedDirs[srcDir]; importer != nil {
+		if resolved, ok := importer.ImportMap[path]; ok {
+			path = resolved
+		}
+	}
+	p := listedPackages[path]
+	if p == nil {
+		return nil, false
+	}
+	return p.buildPackage(), true
+}
+
+// buildPackage returns the package as resolved by go/build, with the imports
+// as written in its source files. The positions of the imports aren't
+// listed.
+func (p *listPackage) buildPackage() *build.Package {
+	source := make(map[string]string)
+	for imp, resolved := range p.ImportMap {
+		source[resolved] = imp
+	}
+	imports := make([]string, len(p.Imports))
+	for i, imp := range p.Imports {
+		if s, ok := source[imp]; ok {
+			imp = s
+		}
+		imports[i] = imp
+	}
+	return &build.Package{
+		ImportPath:   p.ImportPath,
+		Dir:          p.Dir,
+		Name:         p.Name,
+		Goroot:       p.Goroot,
+		GoFiles:      p.GoFiles,
+		CgoFiles:     p.CgoFiles,
+		Imports:      imports,
+		TestImports:  p.TestImports,
+		XTestImports: p.XTestImports,
+	}
+}
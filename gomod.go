@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// replacement is a replace directive of a go.mod file.
+type replacement struct {
+	Old, OldVersion string
+	New, NewVersion string
+}
+
+// replacements holds the replace directives of the main modules.
+var replacements []replacement
+
+// indirectModules holds the modules required by the main modules with an
+// indirect comment, which their own packages don't import.
+var indirectModules map[string]bool
+
+// readGoMod returns the modules required with an indirect comment and the
+// replace directives of the named go.mod file, or nil if it can't be read.
+func readGoMod(gomod string) (indirect []string, reps []replacement) {
+	f, err := os.Open(gomod)
+	if err != nil {
+		return nil, nil
+	}
+	defer f.Close()
+
+	block := ""
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line, comment := s.Text(), ""
+		if i := strings.Index(line, "//"); i >= 0 {
+			line, comment = line[:i], strings.TrimSpace(line[i+2:])
+		}
+		line = strings.TrimSpace(line)
+		verb := block
+		switch {
+		case block != "" && line == ")":
+			block = ""
+			continue
+		case line == "require (" || line == "replace (":
+			block = strings.TrimSuffix(line, " (")
+			continue
+		case strings.HasPrefix(line, "require "), strings.HasPrefix(line, "replace "):
+			verb = line[:len("require")]
+			line = strings.TrimSpace(line[len("require"):])
+		}
+
+		switch verb {
+		case "require":
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			if comment == "indirect" || strings.HasPrefix(comment, "indirect;") {
+				indirect = append(indirect, strings.Trim(fields[0], `"`))
+			}
+		case "replace":
+			sides := strings.SplitN(line, "=>", 2)
+			if len(sides) != 2 {
+				continue
+			}
+			old, new := strings.Fields(sides[0]), strings.Fields(sides[1])
+			if len(old) == 0 || len(new) == 0 {
+				continue
+			}
+			r := replacement{Old: strings.Trim(old[0], `"`), New: strings.Trim(new[0], `"`)}
+			if len(old) > 1 {
+				r.OldVersion = old[1]
+			}
+			if len(new) > 1 {
+				r.NewVersion = new[1]
+			}
+			reps = append(reps, r)
+		}
+	}
+	return indirect, reps
+}
+
+// readMainModule adds the requirements and replacements of the module
+// containing dir to those of the main modules.
+func readMainModule(dir string) {
+	m := moduleOf(dir)
+	if m == nil || m.Version != "" {
+		return
+	}
+	indirect, reps := readGoMod(filepath.Join(m.Dir, "go.mod"))
+	replacements = append(replacements, reps...)
+	for _, p := range indirect {
+		indirectModules[p] = true
+	}
+}
+
+// replacementOf returns the replacement of the module providing the package
+// with the given import path, matching the longest replaced module path.
+func replacementOf(importPath string) (replacement, bool) {
+	var found replacement
+	ok := false
+	for _, r := range replacements {
+		if (importPath == r.Old || strings.HasPrefix(importPath, r.Old+"/")) && len(r.Old) > len(found.Old) {
+			found, ok = r, true
+		}
+	}
+	return found, ok
+}
+
+// local reports whether the module is replaced by a directory rather than by
+// another module.
+func (r replacement) local() bool {
+	return strings.HasPrefix(r.New, "./") || strings.HasPrefix(r.New, "../") || filepath.IsAbs(r.New)
+}
+
+// String returns the replacement module as written in go.mod.
+func (r replacement) String() string {
+	if r.NewVersion == "" {
+		return r.New
+	}
+	return r.New + " " + r.NewVersion
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadGoMod(t *testing.T) {
+	const gomod = `module example.com/app
+
+go 1.21
+
+require example.com/direct v1.0.0
+require example.com/single v1.0.0 // indirect
+
+require (
+	example.com/a v1.2.0
+	example.com/b v0.3.0 // indirect
+	"example.com/c" v0.1.0 // indirect; used by tools
+	example.com/d v0.1.0 // not indirect
+)
+
+replace example.com/a => ../a
+
+replace (
+	example.com/b v0.3.0 => example.com/fork/b v0.3.1
+	example.com/c => ./c // local copy
+)
+`
+	name := filepath.Join(t.TempDir(), "go.mod")
+	if err := ioutil.WriteFile(name, []byte(gomod), 0644); err != nil {
+		t.Fatal(err)
+	}
+	indirect, reps := readGoMod(name)
+
+	wantIndirect := []string{"example.com/single", "example.com/b", "example.com/c"}
+	if !reflect.DeepEqual(indirect, wantIndirect) {
+		t.Errorf("indirect = %v, want %v", indirect, wantIndirect)
+	}
+	wantReps := []replacement{
+		{Old: "example.com/a", New: "../a"},
+		{Old: "example.com/b", OldVersion: "v0.3.0", New: "example.com/fork/b", NewVersion: "v0.3.1"},
+		{Old: "example.com/c", New: "./c"},
+	}
+	if !reflect.DeepEqual(reps, wantReps) {
+		t.Errorf("replacements = %+v, want %+v", reps, wantReps)
+	}
+
+	if indirect, reps := readGoMod(filepath.Join(t.TempDir(), "go.mod")); indirect != nil || reps != nil {
+		t.Errorf("readGoMod of a missing file = %v, %v, want nil", indirect, reps)
+	}
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// node is a package that is part of the rendered graph.
+type node struct {
+	ImportPath string `json:"importPath"`
+	Dir        string `json:"dir"`
+	Goroot     bool   `json:"goroot"`
+	Cgo        bool   `json:"cgo"`
+	Vendored   bool   `json:"vendored"`
+	Module     string `json:"module,omitempty"`
+	Version    string `json:"version,omitempty"`
+	// Replace is the module replacing the module of the package in the
+	// go.mod file of the main module, if any.
+	Replace string `json:"replace,omitempty"`
+	// Local is true if the module of the package is replaced by a directory,
+	// or is another module of the workspace.
+	Local bool `json:"local,omitempty"`
+	// Indirect is true if the module of the package is only an indirect
+	// requirement of the main module.
+	Indirect bool `json:"indirect,omitempty"`
+	// Error is why the package couldn't be imported, in which case it is
+	// only part of the graph with -k, without any imports.
+	Error string `json:"error,omitempty"`
+	// Lines is the number of lines of the non-test Go files of the package,
+	// only counted with -size-lines.
+	Lines int `json:"lines,omitempty"`
+	// Members lists the packages that were merged into this node, if any.
+	Members []string `json:"members,omitempty"`
+
+	// Imports lists the dependencies of the package that are also part of
+	// the graph, in the order they are imported.
+	Imports []string `json:"-"`
+
+	// importTypes maps each import to one of the edge types.
+	importTypes map[string]string
+	// importWeights maps each import to the number of files importing it.
+	importWeights map[string]int
+	// importPos maps each import to the position of its first import
+	// statement.
+	importPos map[string]token.Position
+	// goFiles lists the non-test Go files of the package, relative to Dir.
+	goFiles []string
+	// usesUnsafe is true if the non-test Go files of the package import
+	// unsafe.
+	usesUnsafe bool
+}
+
+// Edge types, depending on which files of the importing package contain the
+// import.
+const (
+	edgeImport = "import"
+	edgeTest   = "test"
+	edgeXTest  = "xtest"
+)
+
+// edge is an import of To by From.
+type edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Type string `json:"type"`
+	// Weight is the number of files of From that import To.
+	Weight int `json:"weight"`
+}
+
+// graph is the filtered dependency graph, ready to be written out in one of
+// the output formats.
+type graph struct {
+	Nodes []*node
+	// Roots are the import paths of the packages given on the command line
+	// that are part of the graph.
+	Roots []string
+}
+
+// newNode returns the node for pkg, without any imports.
+func newNode(pkg *packageInfo) *node {
+	n := &node{
+		ImportPath: pkg.ImportPath,
+		Dir:        pkg.Dir,
+		Goroot:     pkg.Goroot,
+		Cgo:        pkg.Cgo,
+		Vendored:   pkg.Vendored,
+		Error:      pkg.Error,
+		Lines:      pkg.Lines,
+
+		importTypes:   make(map[string]string),
+		importWeights: make(map[string]int),
+		importPos:     make(map[string]token.Position),
+		goFiles:       pkg.GoFiles,
+		usesUnsafe:    pkg.UsesUnsafe,
+	}
+	if m := moduleOf(pkg.Dir); m != nil && !pkg.Goroot {
+		n.Module = m.Path
+		n.Version = m.Version
+	}
+	if r, ok := replacementOf(n.ImportPath); ok && !pkg.Goroot {
+		n.Replace = r.String()
+		n.Local = r.local()
+	}
+	n.Local = n.Local || (n.Module != "" && localModules[n.Module])
+	n.Indirect = n.Module != "" && indirectModules[n.Module]
+	return n
+}
+
+// buildGraph applies the ignore rules to the processed packages and returns
+// the remaining packages and their imports, sorted by import path.
+func buildGraph() *graph {
+	pkgKeys := []string{}
+	for k := range pkgs {
+		pkgKeys = append(pkgKeys, k)
+	}
+	sort.Strings(pkgKeys)
+
+	g := &graph{}
+	for _, pkgName := range pkgKeys {
+		pkg := pkgs[pkgName]
+		if isIgnored(pkg) {
+			continue
+		}
+
+		n := newNode(pkg)
+		g.Nodes = append(g.Nodes, n)
+
+		// Don't render imports from packages in Goroot
+		if pkg.Goroot && !*delveGoroot {
+			continue
+		}
+
+		for _, imp := range pkg.Imports {
+			impPkg := pkgs[imp.Path]
+			if impPkg == nil || isIgnored(impPkg) {
+				continue
+			}
+			n.Imports = append(n.Imports, imp.Path)
+			n.importTypes[imp.Path] = imp.Type
+			n.importWeights[imp.Path] = imp.Weight
+			n.importPos[imp.Path] = imp.Pos
+		}
+	}
+
+	for _, root := range rootPkgs {
+		if pkg := pkgs[root]; pkg != nil && !isIgnored(pkg) {
+			g.Roots = append(g.Roots, root)
+		}
+	}
+	return g
+}
+
+// node returns the node with the given import path, or nil if the package
+// isn't part of the graph.
+func (g *graph) node(importPath string) *node {
+	i := sort.Search(len(g.Nodes), func(i int) bool {
+		return g.Nodes[i].ImportPath >= importPath
+	})
+	if i < len(g.Nodes) && g.Nodes[i].ImportPath == importPath {
+		return g.Nodes[i]
+	}
+	return nil
+}
+
+// edges returns all the edges of the graph, grouped by importing package.
+func (g *graph) edges() []edge {
+	var edges []edge
+	for _, n := range g.Nodes {
+		for _, imp := range n.Imports {
+			edges = append(edges, edge{
+				From:   n.ImportPath,
+				To:     imp,
+				Type:   n.importTypes[imp],
+				Weight: n.importWeights[imp],
+			})
+		}
+	}
+	return edges
+}
+
+// repoRoot guesses the repository root of a package from its import path,
+// assuming the usual host/owner/repo layout for paths starting with a domain
+// name. It returns "" for packages that don't look hosted, such as the
+// standard library.
+func repoRoot(importPath string) string {
+	parts := strings.Split(importPath, "/")
+	if !strings.Contains(parts[0], ".") {
+		return ""
+	}
+	if len(parts) > 3 {
+		parts = parts[:3]
+	}
+	return strings.Join(parts, "/")
+}
+
+// importers returns for each package in the graph the packages importing it,
+// in graph order.
+func (g *graph) importers() map[string][]string {
+	importers := make(map[string][]string)
+	for _, n := range g.Nodes {
+		for _, imp := range n.Imports {
+			importers[imp] = append(importers[imp], n.ImportPath)
+		}
+	}
+	return importers
+}
+
+// layers assigns the nodes of the graph to layers by their distance from the
+// packages that aren't imported by any other package. Nodes that can't be
+// reached that way, such as packages in an import cycle not imported from
+// outside it, are put in the first layer.
+func (g *graph) layers() [][]*node {
+	importers := g.importers()
+	depth := make(map[string]int)
+	var queue []*node
+	for _, n := range g.Nodes {
+		if len(importers[n.ImportPath]) == 0 {
+			depth[n.ImportPath] = 0
+			queue = append(queue, n)
+		}
+	}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, imp := range n.Imports {
+			if _, ok := depth[imp]; !ok {
+				depth[imp] = depth[n.ImportPath] + 1
+				queue = append(queue, g.node(imp))
+			}
+		}
+	}
+
+	var layers [][]*node
+	for _, n := range g.Nodes {
+		d := depth[n.ImportPath]
+		for len(layers) <= d {
+			layers = append(layers, nil)
+		}
+		layers[d] = append(layers[d], n)
+	}
+	return layers
+}
+
+// subgraph returns the graph made of the nodes for which keepNode returns
+// true, and of the edges between them for which keepEdge returns true. A nil
+// keepEdge keeps all of those edges.
+func (g *graph) subgraph(keepNode func(n *node) bool, keepEdge func(from, to string) bool) *graph {
+	kept := make(map[string]bool)
+	for _, n := range g.Nodes {
+		if keepNode(n) {
+			kept[n.ImportPath] = true
+		}
+	}
+
+	sub := &graph{}
+	for _, n := range g.Nodes {
+		if !kept[n.ImportPath] {
+			continue
+		}
+		c := *n
+		c.Imports = nil
+		for _, imp := range n.Imports {
+			if kept[imp] && (keepEdge == nil || keepEdge(n.ImportPath, imp)) {
+				c.Imports = append(c.Imports, imp)
+			}
+		}
+		sub.Nodes = append(sub.Nodes, &c)
+	}
+	for _, root := range g.Roots {
+		if kept[root] {
+			sub.Roots = append(sub.Roots, root)
+		}
+	}
+	return sub
+}
+
+// edgeTypeRank orders the edge types from the strongest to the weakest kind
+// of dependency.
+var edgeTypeRank = map[string]int{
+	edgeImport: 0,
+	edgeTest:   1,
+	edgeXTest:  2,
+}
+
+// merge returns a graph where the nodes for which groupOf returns the same
+// non-empty name are replaced by a single node with that name, listing the
+// merged packages as its members. Imports between merged nodes are combined,
+// adding up their weights, and imports within a merged node are dropped.
+func (g *graph) merge(groupOf func(n *node) string) *graph {
+	nameOf := make(map[string]string)
+	merged := make(map[string]*node)
+	var order []string
+	for _, n := range g.Nodes {
+		name := groupOf(n)
+		grouped := name != ""
+		if !grouped {
+			name = n.ImportPath
+		}
+		nameOf[n.ImportPath] = name
+
+		m, ok := merged[name]
+		if !ok {
+			m = &node{
+				ImportPath:    name,
+				Dir:           n.Dir,
+				Goroot:        n.Goroot,
+				Module:        n.Module,
+				Version:       n.Version,
+				Replace:       n.Replace,
+				Indirect:      n.Indirect,
+				Local:         n.Local,
+				importTypes:   make(map[string]string),
+				importWeights: make(map[string]int),
+			}
+			merged[name] = m
+			order = append(order, name)
+		}
+		if grouped {
+			m.Members = append(m.Members, n.ImportPath)
+		}
+		if m.Dir != n.Dir {
+			m.Dir = ""
+		}
+		if m.Module != n.Module || m.Version != n.Version {
+			m.Module, m.Version = "", ""
+		}
+		if m.Replace != n.Replace {
+			m.Replace = ""
+		}
+		m.Goroot = m.Goroot && n.Goroot
+		m.Indirect = m.Indirect && n.Indirect
+		m.Local = m.Local && n.Local
+		m.Cgo = m.Cgo || n.Cgo
+		m.usesUnsafe = m.usesUnsafe || n.usesUnsafe
+		m.Vendored = m.Vendored || n.Vendored
+		m.Lines += n.Lines
+	}
+
+	for _, n := range g.Nodes {
+		m := merged[nameOf[n.ImportPath]]
+		for _, imp := range n.Imports {
+			to := nameOf[imp]
+			if to == m.ImportPath {
+				continue
+			}
+			typ, ok := m.importTypes[to]
+			if !ok {
+				m.Imports = append(m.Imports, to)
+				typ = n.importTypes[imp]
+			} else if edgeTypeRank[n.importTypes[imp]] < edgeTypeRank[typ] {
+				typ = n.importTypes[imp]
+			}
+			m.importTypes[to] = typ
+			m.importWeights[to] += n.importWeights[imp]
+		}
+	}
+
+	out := &graph{}
+	for _, name := range order {
+		out.Nodes = append(out.Nodes, merged[name])
+	}
+	sort.Slice(out.Nodes, func(i, j int) bool {
+		return out.Nodes[i].ImportPath < out.Nodes[j].ImportPath
+	})
+	seen := make(map[string]bool)
+	for _, root := range g.Roots {
+		if name := nameOf[root]; !seen[name] {
+			seen[name] = true
+			out.Roots = append(out.Roots, name)
+		}
+	}
+	return out
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+)
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID      string `xml:"id,attr"`
+	For     string `xml:"for,attr"`
+	Name    string `xml:"attr.name,attr"`
+	Type    string `xml:"attr.type,attr"`
+	Default string `xml:"default,omitempty"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string `xml:"source,attr"`
+	Target string `xml:"target,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// writeGraphML writes the graph as a GraphML document. Nodes are identified
+// by their import path and carry the label, stdlib, cgo and vendored keys.
+func writeGraphML(w io.Writer, g *graph) error {
+	doc := graphML{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "label", For: "node", Name: "label", Type: "string"},
+			{ID: "stdlib", For: "node", Name: "stdlib", Type: "boolean", Default: "false"},
+			{ID: "cgo", For: "node", Name: "cgo", Type: "boolean", Default: "false"},
+			{ID: "vendored", For: "node", Name: "vendored", Type: "boolean", Default: "false"},
+		},
+		Graph: graphMLGraph{ID: "godep", EdgeDefault: "directed"},
+	}
+	for _, n := range g.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID: n.ImportPath,
+			Data: []graphMLData{
+				{Key: "label", Value: processName(n.ImportPath)},
+				{Key: "stdlib", Value: strconv.FormatBool(n.Goroot)},
+				{Key: "cgo", Value: strconv.FormatBool(n.Cgo)},
+				{Key: "vendored", Value: strconv.FormatBool(n.Vendored)},
+			},
+		})
+	}
+	for _, e := range g.edges() {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{Source: e.From, Target: e.To})
+	}
+
+	return encodeXML(w, doc)
+}
+
+// encodeXML writes v as an indented XML document.
+func encodeXML(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
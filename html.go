@@ -0,0 +1,221 @@
+package main
+
+import (
+	"html/template"
+	"io"
+)
+
+type htmlNode struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+	Color string `json:"color"`
+}
+
+type htmlGraph struct {
+	Nodes      []htmlNode `json:"nodes"`
+	Edges      []edge     `json:"edges"`
+	Horizontal bool       `json:"horizontal"`
+	// Filters holds the current filters when the page is served by the
+	// serve command, which adds a form to change them.
+	Filters *serveFilters `json:"-"`
+}
+
+// writeHTML writes a self-contained HTML page that embeds the graph and a
+// small viewer supporting pan, zoom, search and expanding the neighbors of a
+// node.
+func writeHTML(w io.Writer, g *graph) error {
+	return writeHTMLPage(w, g, nil)
+}
+
+// writeHTMLPage writes the HTML page of writeHTML, with a form to change the
+// given filters if not nil.
+func writeHTMLPage(w io.Writer, g *graph, filters *serveFilters) error {
+	hg := htmlGraph{
+		Nodes:      []htmlNode{},
+		Edges:      g.edges(),
+		Horizontal: *horizontal,
+		Filters:    filters,
+	}
+	if hg.Edges == nil {
+		hg.Edges = []edge{}
+	}
+	for _, n := range g.Nodes {
+		hg.Nodes = append(hg.Nodes, htmlNode{
+			ID:    n.ImportPath,
+			Label: processName(n.ImportPath),
+			Color: rgbColor(nodeColor(n)),
+		})
+	}
+	return htmlTemplate.Execute(w, hg)
+}
+
+var htmlTemplate = template.Must(template.New("html").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>godepgraph</title>
+<style>
+html, body { margin: 0; height: 100%; font-family: sans-serif; font-size: 12px; }
+#toolbar { position: fixed; top: 0; left: 0; right: 0; padding: 6px; background: #f4f4f4; border-bottom: 1px solid #ccc; }
+#toolbar input { width: 300px; }
+#info { margin-left: 12px; color: #555; }
+#filters { display: inline; margin-left: 12px; }
+svg { width: 100%; height: 100%; cursor: move; }
+.node rect { stroke: #333; stroke-width: 1; }
+.node text { pointer-events: none; }
+.node { cursor: pointer; }
+.edge { stroke: #999; stroke-width: 1; fill: none; marker-end: url(#arrow); }
+.dim { opacity: 0.12; }
+.match rect { stroke: red; stroke-width: 3; }
+.hidden { display: none; }
+</style>
+</head>
+<body>
+<div id="toolbar">
+<input id="search" type="search" placeholder="Search packages">
+<button id="reset">Reset</button>
+<span id="info"></span>
+{{with .Filters}}<form id="filters" method="get">
+<input name="focus" value="{{.Focus}}" placeholder="Focus on package" style="width: 200px">
+<input name="hops" type="number" min="1" value="{{.Hops}}" title="Hops from the focused package" style="width: 4em">
+<input name="exclude" value="{{.Exclude}}" placeholder="Exclude packages matching" style="width: 200px">
+<label><input name="s" type="checkbox" value="1"{{if .IgnoreStdlib}} checked{{end}} style="width: auto">Hide the standard library</label>
+<button>Apply</button>
+</form>{{end}}
+</div>
+<svg id="canvas">
+<defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="6" markerHeight="6" orient="auto"><path d="M0,0 L10,5 L0,10 z" fill="#999"/></marker></defs>
+<g id="viewport"></g>
+</svg>
+<script>
+var graph = {{.}};
+(function() {
+	var ns = "http://www.w3.org/2000/svg";
+	var svg = document.getElementById("canvas");
+	var viewport = document.getElementById("viewport");
+	var info = document.getElementById("info");
+	var nodes = {}, out = {}, inc = {};
+	graph.nodes.forEach(function(n) { nodes[n.id] = n; out[n.id] = []; inc[n.id] = []; });
+	graph.edges.forEach(function(e) { out[e.from].push(e.to); inc[e.to].push(e.from); });
+
+	// Assign each node to a layer by its distance from the packages nobody imports.
+	var depth = {}, queue = [];
+	graph.nodes.forEach(function(n) { if (inc[n.id].length === 0) { depth[n.id] = 0; queue.push(n.id); } });
+	while (queue.length > 0) {
+		var id = queue.shift();
+		out[id].forEach(function(to) { if (!(to in depth)) { depth[to] = depth[id] + 1; queue.push(to); } });
+	}
+	var layers = [];
+	graph.nodes.forEach(function(n) {
+		var d = n.id in depth ? depth[n.id] : 0;
+		(layers[d] = layers[d] || []).push(n);
+	});
+	layers.forEach(function(layer, d) {
+		layer.forEach(function(n, i) {
+			n.w = n.label.length * 7 + 16;
+			if (graph.horizontal) {
+				n.x = d * 300;
+				n.y = (i - (layer.length - 1) / 2) * 40;
+			} else {
+				n.x = (i - (layer.length - 1) / 2) * 220;
+				n.y = d * 100;
+			}
+		});
+	});
+
+	var edgeEls = [], nodeEls = {};
+	graph.edges.forEach(function(e) {
+		var a = nodes[e.from], b = nodes[e.to];
+		var line = document.createElementNS(ns, "line");
+		line.setAttribute("class", "edge");
+		line.setAttribute("x1", a.x); line.setAttribute("y1", a.y + 10);
+		line.setAttribute("x2", b.x); line.setAttribute("y2", b.y - 10);
+		viewport.appendChild(line);
+		edgeEls.push({el: line, from: e.from, to: e.to});
+	});
+	graph.nodes.forEach(function(n) {
+		var g = document.createElementNS(ns, "g");
+		g.setAttribute("class", "node");
+		g.setAttribute("transform", "translate(" + n.x + "," + n.y + ")");
+		var rect = document.createElementNS(ns, "rect");
+		rect.setAttribute("x", -n.w / 2); rect.setAttribute("y", -10);
+		rect.setAttribute("width", n.w); rect.setAttribute("height", 20);
+		rect.setAttribute("fill", n.color);
+		var text = document.createElementNS(ns, "text");
+		text.setAttribute("text-anchor", "middle"); text.setAttribute("y", 4);
+		text.textContent = n.label;
+		var title = document.createElementNS(ns, "title");
+		title.textContent = n.id + "\nimports: " + out[n.id].length + ", imported by: " + inc[n.id].length;
+		g.appendChild(title); g.appendChild(rect); g.appendChild(text);
+		g.addEventListener("click", function(ev) { ev.stopPropagation(); expand(n.id); });
+		viewport.appendChild(g);
+		nodeEls[n.id] = g;
+	});
+
+	// Clicking a node focuses it and its neighbors; clicking further nodes
+	// expands the focused set with their neighbors.
+	var focused = null;
+	function expand(id) {
+		focused = focused || {};
+		focused[id] = true;
+		out[id].forEach(function(to) { focused[to] = true; });
+		inc[id].forEach(function(from) { focused[from] = true; });
+		update();
+	}
+	function update() {
+		var shown = 0;
+		graph.nodes.forEach(function(n) {
+			var on = !focused || focused[n.id];
+			if (on) { shown++; }
+			nodeEls[n.id].classList.toggle("dim", !on);
+		});
+		edgeEls.forEach(function(e) {
+			e.el.classList.toggle("dim", !!focused && !(focused[e.from] && focused[e.to]));
+		});
+		info.textContent = shown + " of " + graph.nodes.length + " packages, " + graph.edges.length + " imports";
+	}
+
+	document.getElementById("search").addEventListener("input", function() {
+		var q = this.value.toLowerCase();
+		var first = null;
+		graph.nodes.forEach(function(n) {
+			var match = q !== "" && n.id.toLowerCase().indexOf(q) >= 0;
+			nodeEls[n.id].classList.toggle("match", match);
+			if (match && !first) { first = n; }
+		});
+		if (first) { center(first); }
+	});
+	document.getElementById("reset").addEventListener("click", function() {
+		focused = null;
+		update();
+	});
+
+	var scale = 1, tx = 0, ty = 0;
+	function apply() { viewport.setAttribute("transform", "translate(" + tx + "," + ty + ") scale(" + scale + ")"); }
+	function center(n) {
+		tx = svg.clientWidth / 2 - n.x * scale;
+		ty = svg.clientHeight / 2 - n.y * scale;
+		apply();
+	}
+	svg.addEventListener("wheel", function(ev) {
+		ev.preventDefault();
+		var f = ev.deltaY < 0 ? 1.1 : 1 / 1.1;
+		tx = ev.clientX - (ev.clientX - tx) * f;
+		ty = ev.clientY - (ev.clientY - ty) * f;
+		scale *= f;
+		apply();
+	});
+	var drag = null;
+	svg.addEventListener("mousedown", function(ev) { drag = {x: ev.clientX - tx, y: ev.clientY - ty}; });
+	window.addEventListener("mousemove", function(ev) { if (drag) { tx = ev.clientX - drag.x; ty = ev.clientY - drag.y; apply(); } });
+	window.addEventListener("mouseup", function() { drag = null; });
+
+	tx = svg.clientWidth / 2;
+	ty = 60;
+	apply();
+	update();
+})();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+// importImpact is the cost of a direct import of a root package: the
+// packages that would no longer be depended on without it.
+type importImpact struct {
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+	Removed  int      `json:"removed"`
+	Packages []string `json:"packages"`
+}
+
+// impacts returns the impact of each import of the root packages, from the
+// most to the least costly.
+func (g *graph) impacts() []importImpact {
+	all := g.reachable(g.Roots)
+	var impacts []importImpact
+	for _, root := range g.Roots {
+		for _, imp := range g.node(root).Imports {
+			left := g.reachableExcept(g.Roots, func(from, to string) bool {
+				return from == root && to == imp
+			})
+			packages := []string{}
+			for _, n := range g.Nodes {
+				if all[n.ImportPath] && !left[n.ImportPath] {
+					packages = append(packages, n.ImportPath)
+				}
+			}
+			impacts = append(impacts, importImpact{
+				From:     root,
+				To:       imp,
+				Removed:  len(packages),
+				Packages: packages,
+			})
+		}
+	}
+	sort.SliceStable(impacts, func(i, j int) bool {
+		return impacts[i].Removed > impacts[j].Removed
+	})
+	return impacts
+}
+
+// writeImpact writes the impact of each import of the root packages, as a
+// table or as JSON.
+func writeImpact(w io.Writer, g *graph, asJSON bool) error {
+	impacts := g.impacts()
+	if asJSON {
+		if impacts == nil {
+			impacts = []importImpact{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(impacts)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "PACKAGE\tIMPORT\tREMOVED")
+	for _, i := range impacts {
+		fmt.Fprintf(tw, "%s\t%s\t%d\n", processName(i.From), processName(i.To), i.Removed)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+type jsonGraph struct {
+	Nodes []*node `json:"nodes"`
+	Edges []edge  `json:"edges"`
+}
+
+// writeJSON writes the graph as a JSON document with a list of nodes and a
+// list of edges.
+func writeJSON(w io.Writer, g *graph) error {
+	jg := jsonGraph{
+		Nodes: g.Nodes,
+		Edges: g.edges(),
+	}
+	if jg.Nodes == nil {
+		jg.Nodes = []*node{}
+	}
+	if jg.Edges == nil {
+		jg.Edges = []edge{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(jg)
+}
+
+// readJSON reads a graph previously written with the json output format.
+func readJSON(name string) (*graph, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var jg jsonGraph
+	if err := json.NewDecoder(f).Decode(&jg); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %s", name, err)
+	}
+
+	g := &graph{Nodes: jg.Nodes}
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		return g.Nodes[i].ImportPath < g.Nodes[j].ImportPath
+	})
+	for _, n := range g.Nodes {
+		n.importTypes = make(map[string]string)
+		n.importWeights = make(map[string]int)
+	}
+	for _, e := range jg.Edges {
+		n := g.node(e.From)
+		if n == nil || g.node(e.To) == nil {
+			return nil, fmt.Errorf("failed to read %s: edge %s -> %s refers to an unknown package", name, e.From, e.To)
+		}
+		n.Imports = append(n.Imports, e.To)
+		n.importTypes[e.To] = e.Type
+		n.importWeights[e.To] = e.Weight
+	}
+	return g, nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// exceededLimit returns the -max-nodes or -max-edges limit that a graph with
+// the given numbers of packages and imports has reached, or "" if there is
+// none. The scan stops at the number of packages given to -max-nodes, and
+// after the package whose imports exceed the number given to -max-edges.
+func exceededLimit(nodes, edges int) string {
+	switch {
+	case *maxNodes > 0 && nodes >= *maxNodes:
+		return fmt.Sprintf("%d packages (-max-nodes)", *maxNodes)
+	case *maxEdges > 0 && edges > *maxEdges:
+		return fmt.Sprintf("%d imports (-max-edges)", *maxEdges)
+	}
+	return ""
+}
+
+// warnTruncated warns that the scan was stopped after reaching a limit, and
+// suggests ways to get a smaller graph.
+func warnTruncated(w io.Writer, limit string, queued int) {
+	fmt.Fprintf(w, "warning: stopped scanning after %s, with %d package(s) left; the graph is incomplete\n", limit, queued)
+	fmt.Fprintln(w, "to get a smaller graph, try ignoring the standard library with -s, limiting the depth with -l, keeping only some packages with -o, or merging packages with -collapse or -granularity module")
+}
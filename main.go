@@ -1,17 +1,25 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"go/build"
+	"io"
+	"io/ioutil"
 	"log"
 	"os"
+	"os/exec"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 var (
-	pkgs        map[string]*build.Package
+	pkgs        map[string]*packageInfo
+	rootPkgs    []string
 	ids         map[string]int
 	colorSubst  map[string]string
 	prefixSubst map[string]string
@@ -23,34 +31,397 @@ var (
 	}
 	ignoredPrefixes []string
 	onlyPrefixes    []string
+	includeRegexp   *regexp.Regexp
+	excludeRegexp   *regexp.Regexp
+	excludeGlobs    []*regexp.Regexp
 
 	ignoreStdlib       = flag.Bool("s", false, "ignore packages in the Go standard library")
 	delveGoroot        = flag.Bool("d", false, "show dependencies of packages in the Go standard library")
 	ignorePrefixes     = flag.String("p", "", "a comma-separated list of prefixes to ignore")
 	ignorePackages     = flag.String("i", "", "a comma-separated list of packages to ignore")
 	onlyPrefix         = flag.String("o", "", "a comma-separated list of prefixes to include")
+	includePattern     = flag.String("include-re", "", "only include the packages whose import path matches this regular expression")
+	excludePattern     = flag.String("exclude-re", "", "ignore the packages whose import path matches this regular expression")
+	excludeGlobList    = flag.String("x", "", "a comma-separated list of globs of packages to ignore, where * matches within a path element and ** across them, e.g. github.com/*/legacy/**")
 	tagList            = flag.String("tags", "", "a comma-separated list of build tags to consider satisified during the build")
+	useCache           = flag.Bool("cache", false, "cache the resolved packages in the user cache directory, and reuse them while their files are unchanged")
+	incrementalFile    = flag.String("incremental", "", "save the resolved packages and the hashes of their files to this file, and only resolve again the packages whose files changed since")
+	useGoList          = flag.Bool("golist", false, "resolve the packages with a single go list -deps -json invocation instead of importing each one with go/build, which is much faster on large repositories but doesn't give the positions of the imports")
+	keepGoing          = flag.Bool("k", false, "keep scanning when a package can't be imported, showing it as an error node, and exit with status 2 once the output is written")
+	modMode            = flag.String("mod", "", "the module download mode, as with the go command: readonly, vendor or mod; with vendor, fail if a dependency isn't in the vendor directory")
+	diffTagList        = flag.String("diff-tags", "", "compare the graph with the one built with this comma-separated list of build tags instead of -tags, and write the differences")
+	platform           = flag.String("platform", "", "build for this platform given as goos/goarch, e.g. windows/amd64, instead of the host platform")
+	diffPlatform       = flag.String("diff-platform", "", "compare the graph with the one built for this platform given as goos/goarch instead of -platform, and write the differences")
 	horizontal         = flag.Bool("horizontal", false, "lay out the dependency graph horizontally instead of vertically")
 	includeTests       = flag.Bool("t", false, "include test packages")
-	maxLevel           = flag.Int("l", 256, "max level of go dependency graph")
+	maxLevel           = flag.Int("l", 256, "max level of go dependency graph, the shortest distance of a package from the root packages")
 	prefixSubstitution = flag.String("r", "", "a comma-separeated list of prefix replacement, e.g. github.com=g")
 	colorSpec          = flag.String("c", "", "a comma-separated list of color spec, e.g. github.com=red")
+	render             = flag.Bool("render", false, "render the graph with Graphviz dot, to png unless -T is given")
+	renderFormat       = flag.String("T", "", "render the graph with Graphviz dot to the given image format: svg, png or pdf")
+	outputFile         = flag.String("output", "", "write the output to a file instead of stdout, guessing the format from its extension unless -format or -T is given")
+	pathsFrom          = flag.String("from", "", "with -to, only show import paths starting at this package instead of the packages given as arguments")
+	pathsTo            = flag.String("to", "", "only show the packages and imports on an import path to this package")
+	shortestPath       = flag.Bool("shortest", false, "with -to, only show the shortest import path")
+	reverse            = flag.String("reverse", "", "only show the packages that import this package, directly or indirectly")
+	without            = flag.String("without", "", "a comma-separated list of packages, or of imports given as from=to, to remove from the graph along with the packages only they depend on")
+	focus              = flag.String("focus", "", "only show the packages at most -hops imports away from this package, in either direction")
+	focusHops          = flag.Int("hops", 1, "with -focus, the number of imports to follow from the package")
+	cyclesOnly         = flag.Bool("cycles-only", false, "only show packages and imports that are part of an import cycle")
+	condense           = flag.Bool("condense", false, "merge the packages of each import cycle into a single node")
+	godCA              = flag.Int("god-ca", 0, "with -god-ce, report and highlight the packages imported by more packages than this that also import more than -god-ce")
+	godCE              = flag.Int("god-ce", 0, "with -god-ca, report and highlight the packages importing more packages than this that are also imported by more than -god-ca")
+	findDuplicates     = flag.Bool("duplicates", false, "report and highlight the modules that appear under several major versions, or that look like forks of the same repository")
+	serveAddr          = flag.String("addr", "localhost:8080", "the address serve listens on")
+	topCount           = flag.Int("n", 10, "the number of packages listed by top")
+	statsSort          = flag.String("sort", "name", "sort order of the stats report: name, ca, ce, i, a, d, deps or b")
+	collapseStd        = flag.Bool("collapse-std", false, "merge the packages of the Go standard library into a single node")
+	workspace          = flag.Bool("workspace", false, "also scan all the packages of the modules of the go.work workspace, and cluster them by module")
+	scanNested         = flag.Bool("nested", false, "with a relative pattern ending in /..., also scan the packages of the modules nested in its directory tree")
+	clusterModules     = flag.Bool("cluster-modules", false, "group the packages of each module in a cluster labeled with the module path")
+	granularity        = flag.String("granularity", "package", "draw a node per package, or per module with module")
+	collapseList       = flag.String("collapse", "", "a comma-separated list of prefixes whose packages are merged into a single node each")
+	reduce             = flag.Bool("reduce", false, "remove the imports of a package that it also depends on through its other imports")
+	countDeps          = flag.Bool("count-deps", false, "add the number of transitive dependencies to the package labels")
+	distanceColors     = flag.Bool("color-distance", false, "color packages by their distance from the main sequence, from green to red")
+	cgoColors          = flag.Bool("color-cgo", false, "color the packages that depend on cgo without using it themselves, since they can't be built with CGO_ENABLED=0 either")
+	showVersions       = flag.Bool("versions", false, "add the module version to the labels of the packages of external modules")
+	replacedColors     = flag.Bool("color-replaced", false, "color the packages of the modules replaced in the go.mod file of the main module")
+	unsafeColors       = flag.Bool("color-unsafe", false, "color the packages outside of the standard library that import unsafe, and those that depend on them")
+	sizeByLines        = flag.Bool("size-lines", false, "scale the packages by their number of lines of Go code")
+	edgeWeights        = flag.Bool("weights", false, "label the imports with the number of files importing the package, and draw them thicker accordingly")
+	checkInternal      = flag.Bool("internal", false, "exit with status 1 if an import breaks the visibility rules of internal packages or crosses a module boundary to reach one")
+	maxNodes           = flag.Int("max-nodes", 0, "stop scanning with a warning once the graph has this many packages, instead of producing a graph too large to lay out")
+	maxEdges           = flag.Int("max-edges", 0, "stop scanning with a warning once the graph has more imports than this, instead of producing a graph too large to lay out")
+	maxPackages        = flag.Int("max-packages", 0, "exit with status 1 if the graph has more packages than this")
+	maxDeps            = flag.Int("max-deps", 0, "exit with status 1 if a package has more transitive dependencies than this")
+	maxDepth           = flag.Int("max-depth", 0, "exit with status 1 if an import chain from the root packages is longer than this")
+	layersFile         = flag.String("layers", "", "exit with status 1 if an import breaks the layering declared in this file")
+	baselineFile       = flag.String("baseline", "", "exit with status 1 if the graph has an import that isn't listed in this baseline file")
+	writeBaselineFile  = flag.String("write-baseline", "", "write the imports of the graph to this baseline file")
+	dryRun             = flag.Bool("dry-run", false, "instead of the graph, write the number of packages and imports kept in the graph, and of those left out by each filter")
+	showSummary        = flag.Bool("stats", false, "report how long loading, analyzing and writing the graph took, and how many packages were scanned, ignored and filtered out, to stderr")
+	cpuProfile         = flag.String("cpuprofile", "", "write a CPU profile to this file, to be read with go tool pprof")
+	memProfile         = flag.String("memprofile", "", "write a memory profile to this file before exiting, to be read with go tool pprof")
+	watch              = flag.Bool("watch", false, "with -output, write the output again each time a file of one of the scanned packages changes, until interrupted")
+	showVersion        = flag.Bool("version", false, "print the version of godepgraph and the Go version it was built with, and exit")
+	configFile         = flag.String("config", "", "read the default flags from this configuration file instead of the "+configFileName+" file found in the current directory or its parents")
+	verbose            = flag.Bool("v", false, "report the progress of the scan to stderr")
+	veryVerbose        = flag.Bool("vv", false, "like -v, and also report each package that is skipped, and the flag that skips it")
+	quiet              = flag.Bool("q", false, "only report errors and failed checks to stderr, leaving out the import cycles and the other reports")
+	outputFormat       = flag.String("format", "dot", "output format: dot, json, ndjson, yaml, graphml, d2, csv, tgf, gexf, cytoscape, html, d3, d3-html, tree, matrix, markdown, tikz, cypher, sql, sqlite, proto, protojson, excalidraw or cyclonedx")
 
 	buildTags    []string
 	buildContext = build.Default
+	denyRules    rules
+	allowRules   rules
+
+	formats = map[string]func(io.Writer, *graph) error{
+		"dot":        writeDot,
+		"json":       writeJSON,
+		"graphml":    writeGraphML,
+		"d2":         writeD2,
+		"csv":        writeCSV,
+		"tgf":        writeTGF,
+		"gexf":       writeGEXF,
+		"cytoscape":  writeCytoscape,
+		"html":       writeHTML,
+		"d3":         writeD3,
+		"d3-html":    writeD3HTML,
+		"tree":       writeTree,
+		"matrix":     writeMatrix,
+		"yaml":       writeYAML,
+		"cyclonedx":  writeCycloneDX,
+		"markdown":   writeMarkdown,
+		"tikz":       writeTikZ,
+		"ndjson":     writeNDJSON,
+		"cypher":     writeCypher,
+		"sql":        writeSQL,
+		"proto":      writeProto,
+		"protojson":  writeProtoJSON,
+		"excalidraw": writeExcalidraw,
+	}
 )
 
 func main() {
-	pkgs = make(map[string]*build.Package)
 	ids = make(map[string]int)
 	colorSubst = make(map[string]string)
 	prefixSubst = make(map[string]string)
-	flag.Parse()
+	flag.Var(&denyRules, "deny", "exit with status 1 if a package matching from imports one matching to, given as from=to (repeatable)")
+	flag.Var(&allowRules, "allow", "exit with status 1 if a package matching from imports one matching neither from nor any of the comma-separated patterns in to, given as from=to (repeatable)")
+	flag.Usage = usage
+	sc := subcommandOf(os.Args[1:])
+	if sc != nil {
+		flag.CommandLine.Parse(sc.flatArgs(os.Args[2:]))
+	} else {
+		flag.Parse()
+	}
+	if *showVersion {
+		writeVersion(os.Stdout)
+		return
+	}
+	if err := loadEnv(); err != nil {
+		fatal(err)
+	}
+	if err := loadConfig(*configFile); err != nil {
+		fatal(err)
+	}
+	switch {
+	case *quiet:
+		verbosity = levelQuiet
+	case *veryVerbose:
+		verbosity = levelDebug
+	case *verbose:
+		verbosity = levelVerbose
+	}
+
+	args, err := readStdinArgs(flag.Args(), os.Stdin)
+	if err != nil {
+		fatal(err)
+	}
+
+	if len(args) < 1 && !*workspace {
+		fatal("need one package name to process")
+	}
 
-	args := flag.Args()
+	if *granularity != "package" && *granularity != "module" {
+		fatalf("unknown granularity: %s", *granularity)
+	}
+	if *pathsFrom != "" && *pathsTo == "" {
+		fatal("-from requires -to")
+	}
+	if isFlagConfigured("hops") && *focus == "" {
+		fatal("-hops requires -focus")
+	}
+	if isFlagConfigured("god-ca") != isFlagConfigured("god-ce") {
+		fatal("-god-ca and -god-ce must be given together")
+	}
+	if *useGoList && (*useCache || *incrementalFile != "") {
+		fatal("-golist can't be used with -cache or -incremental")
+	}
+	if *useGoList && *edgeWeights {
+		fatal("-weights can't be used with -golist, which doesn't tell which files import each package")
+	}
 
-	if len(args) < 1 {
-		log.Fatal("need one package name to process")
+	var command, why string
+	var common []string
+	var diffRefs []string
+	var first string
+	// The packages given to the subcommands without a command of their own,
+	// graph and paths, are never taken for one of the flat command line.
+	if len(args) > 0 && (sc == nil || sc.command != "") {
+		first = args[0]
+	}
+	switch first {
+	case "why":
+		if len(args) != 3 {
+			fatal("usage: godepgraph why <package> <dependency>")
+		}
+		command, why, args = args[0], args[2], args[1:2]
+		if isFlagSet("format") {
+			fatal("why can only be written as text")
+		}
+	case "common":
+		if len(args) < 4 {
+			fatal("usage: godepgraph common <package> <package> <root>...")
+		}
+		command, common, args = args[0], args[1:3], args[3:]
+		if isFlagSet("format") {
+			fatal("common can only be written as text")
+		}
+	case "orphans":
+		if len(args) != 2 {
+			fatal("usage: godepgraph orphans <package>")
+		}
+		command, args = args[0], args[1:]
+		if isFlagSet("format") {
+			fatal("orphans can only be written as text")
+		}
+	case "diff":
+		if len(args) == 3 && strings.HasSuffix(args[1], ".json") && strings.HasSuffix(args[2], ".json") {
+			command, diffRefs, args = args[0], args[1:3], nil
+		} else if len(args) >= 4 {
+			command, diffRefs, args = args[0], args[1:3], args[3:]
+		} else {
+			fatal("usage: godepgraph diff <old-ref> <new-ref> <package>... or godepgraph diff <old.json> <new.json>")
+		}
+		if isFlagSet("format") && *outputFormat != "dot" && *outputFormat != "json" {
+			fatal("diff can only be written as text or with -format dot or json")
+		}
+	case "chain":
+		if len(args) < 2 {
+			fatal("usage: godepgraph chain <package>...")
+		}
+		command, args = args[0], args[1:]
+		if isFlagSet("format") {
+			fatal("chain can only be written as text")
+		}
+	case "cgo":
+		if len(args) < 2 {
+			fatal("usage: godepgraph cgo <package>...")
+		}
+		command, args = args[0], args[1:]
+		if isFlagSet("format") {
+			fatal("cgo can only be written as text")
+		}
+	case "unsafe":
+		if len(args) < 2 {
+			fatal("usage: godepgraph unsafe <package>...")
+		}
+		command, args = args[0], args[1:]
+		if isFlagSet("format") {
+			fatal("unsafe can only be written as text")
+		}
+	case "top":
+		if len(args) < 2 {
+			fatal("usage: godepgraph top <package>...")
+		}
+		command, args = args[0], args[1:]
+		if isFlagSet("format") {
+			fatal("top can only be written as text")
+		}
+		if *topCount < 1 {
+			fatal("-n must be at least 1")
+		}
+	case "testonly":
+		if len(args) < 2 {
+			fatal("usage: godepgraph testonly <package>...")
+		}
+		command, args = args[0], args[1:]
+		if isFlagSet("format") {
+			fatal("testonly can only be written as text")
+		}
+		*includeTests = true
+	case "modgraph":
+		if len(args) < 2 {
+			fatal("usage: godepgraph modgraph <package>...")
+		}
+		command, args = args[0], args[1:]
+		if isFlagSet("format") && *outputFormat != "dot" {
+			fatal("modgraph can only be written with -format dot")
+		}
+	case "explore":
+		if len(args) < 2 {
+			fatal("usage: godepgraph explore <package>...")
+		}
+		command, args = args[0], args[1:]
+		if isFlagSet("format") {
+			fatal("explore can only be written as text")
+		}
+		if contains(flag.Args(), "-") {
+			fatal("explore reads its commands from stdin, so - can't be used")
+		}
+	case "serve":
+		if len(args) < 2 {
+			fatal("usage: godepgraph serve <package>...")
+		}
+		command, args = args[0], args[1:]
+		if isFlagSet("format") || isFlagSet("output") || isFlagSet("T") || isFlagSet("watch") {
+			fatal("serve can't be used with -format, -output, -T or -watch")
+		}
+	case "dominators":
+		if len(args) < 2 {
+			fatal("usage: godepgraph dominators <package>...")
+		}
+		command, args = args[0], args[1:]
+		if isFlagSet("format") {
+			fatal("dominators can only be written as text")
+		}
+	case "impact":
+		if len(args) < 2 {
+			fatal("usage: godepgraph impact <package>...")
+		}
+		command, args = args[0], args[1:]
+		if isFlagSet("format") && *outputFormat != "dot" && *outputFormat != "json" {
+			fatal("impact can only be written as text or with -format json")
+		}
+	case "stats":
+		if len(args) < 2 {
+			fatal("usage: godepgraph stats <package>...")
+		}
+		command, args = args[0], args[1:]
+		if isFlagSet("format") && *outputFormat != "dot" && *outputFormat != "json" {
+			fatal("stats can only be written as text or with -format json")
+		}
+		if statsSorts[*statsSort] == nil {
+			fatalf("unknown stats sort order: %s", *statsSort)
+		}
+	case "check":
+		if len(args) < 2 {
+			fatal("usage: godepgraph check <package>...")
+		}
+		command, args = args[0], args[1:]
+		if isFlagSet("format") || isFlagSet("output") || isFlagSet("T") {
+			fatal("check doesn't write any output")
+		}
+	}
+	// A -format set by an environment variable or the configuration file is
+	// meant for the graph, and is left out by the commands writing their own
+	// output, as are -output and -T by those writing none.
+	switch command {
+	case "":
+	case "check", "serve":
+		resetPresetFlags("format", "output", "T", "watch")
+	default:
+		resetPresetFlags("format")
+	}
+
+	diffBuild := isFlagConfigured("diff-tags") || *diffPlatform != ""
+	if diffBuild {
+		if command != "" {
+			fatalf("-diff-tags and -diff-platform can't be used with %s", command)
+		}
+		if isFlagSet("format") && *outputFormat != "dot" && *outputFormat != "json" {
+			fatal("-diff-tags and -diff-platform can only be written as text or with -format dot or json")
+		}
+	}
+	if *watch {
+		if *outputFile == "" {
+			fatal("-watch requires -output")
+		}
+		if command == "diff" || diffBuild {
+			fatal("-watch can't be used with diff, -diff-tags or -diff-platform")
+		}
+	}
+
+	if *outputFile != "" && !isFlagSet("format") && *renderFormat == "" {
+		*outputFormat, *renderFormat = formatForFile(*outputFile)
+	}
+	streamGraph = command == "" && *outputFormat == "ndjson"
+
+	write, ok := formats[*outputFormat]
+	if !ok && *outputFormat != "sqlite" {
+		fatalf("unknown output format: %s", *outputFormat)
+	}
+	if *outputFormat == "sqlite" {
+		if *outputFile == "" {
+			fatal("-format sqlite requires -output")
+		}
+		sqlite3, err := exec.LookPath("sqlite3")
+		if err != nil {
+			fatalf("-format sqlite requires the sqlite3 command: %s", err)
+		}
+		write = func(w io.Writer, g *graph) error {
+			return writeSQLite(sqlite3, *outputFile, g)
+		}
+	}
+	if *render && *renderFormat == "" {
+		*renderFormat = "png"
+	}
+	if *renderFormat != "" {
+		if *outputFormat != "dot" {
+			fatal("-T can only be used with the dot output format")
+		}
+		if !renderFormats[*renderFormat] {
+			fatalf("unsupported render format: %s", *renderFormat)
+		}
+		dot, err := exec.LookPath("dot")
+		if err != nil {
+			fatalf("rendering requires Graphviz dot: %s", err)
+		}
+		write = func(w io.Writer, g *graph) error {
+			return renderDot(w, g, dot, *renderFormat)
+		}
 	}
 
 	if *ignorePrefixes != "" {
@@ -59,6 +430,27 @@ func main() {
 	if *onlyPrefix != "" {
 		onlyPrefixes = strings.Split(*onlyPrefix, ",")
 	}
+	if *excludeGlobList != "" {
+		for _, glob := range strings.Split(*excludeGlobList, ",") {
+			re, err := globRegexp(glob)
+			if err != nil {
+				fatalf("wrong glob: %s", glob)
+			}
+			excludeGlobs = append(excludeGlobs, re)
+		}
+	}
+	if *includePattern != "" {
+		var err error
+		if includeRegexp, err = regexp.Compile(*includePattern); err != nil {
+			fatalf("wrong -include-re: %s", err)
+		}
+	}
+	if *excludePattern != "" {
+		var err error
+		if excludeRegexp, err = regexp.Compile(*excludePattern); err != nil {
+			fatalf("wrong -exclude-re: %s", err)
+		}
+	}
 	if *ignorePackages != "" {
 		for _, p := range strings.Split(*ignorePackages, ",") {
 			ignored[p] = true
@@ -68,13 +460,36 @@ func main() {
 		buildTags = strings.Split(*tagList, ",")
 	}
 	buildContext.BuildTags = buildTags
+	if *modMode != "" {
+		if err := setModMode(*modMode); err != nil {
+			fatal(err)
+		}
+	}
+	if *platform != "" {
+		goos, goarch, err := parsePlatform(&buildContext, *platform)
+		if err != nil {
+			fatalf("wrong platform: %s", err)
+		}
+		setPlatform(&buildContext, goos, goarch)
+	}
+	var diffTags []string
+	if *diffTagList != "" {
+		diffTags = strings.Split(*diffTagList, ",")
+	}
+	var diffGOOS, diffGOARCH string
+	if *diffPlatform != "" {
+		var err error
+		if diffGOOS, diffGOARCH, err = parsePlatform(&buildContext, *diffPlatform); err != nil {
+			fatalf("wrong platform: %s", err)
+		}
+	}
 
 	if *colorSpec != "" {
 		colors := strings.Split(*colorSpec, ",")
 		for _, c := range colors {
 			spec := strings.Split(c, "=")
 			if len(spec) != 2 {
-				log.Fatalf("wrong color spec: %s", c)
+				fatalf("wrong color spec: %s", c)
 			}
 			colorSubst[spec[0]] = spec[1]
 		}
@@ -86,7 +501,7 @@ func main() {
 			spec := strings.Split(p, "=")
 			specLen := len(spec)
 			if specLen < 1 || specLen > 2 {
-				log.Fatalf("wrong prefix substitution spec: %s", spec)
+				fatalf("wrong prefix substitution spec: %s", spec)
 			} else if specLen == 1 {
 				prefixSubst[spec[0]] = ""
 			} else if specLen == 2 {
@@ -95,63 +510,554 @@ func main() {
 		}
 	}
 
-	cwd, err := os.Getwd()
+	out, err := createOutput(*outputFile)
 	if err != nil {
-		log.Fatalf("failed to get cwd: %s", err)
+		fatalf("failed to create output: %s", err)
 	}
-	for _, a := range args {
-		if err := processPackage(cwd, a, 0); err != nil {
-			log.Fatal(err)
+	switch command {
+	case "why":
+		write = func(w io.Writer, g *graph) error {
+			return writeWhy(w, g, why)
+		}
+	case "common":
+		write = func(w io.Writer, g *graph) error {
+			return writeCommon(w, g, common[0], common[1])
+		}
+	case "orphans":
+		root := args[0]
+		write = func(w io.Writer, g *graph) error {
+			return writeOrphans(w, root)
+		}
+	case "chain":
+		write = writeChain
+	case "cgo":
+		write = writeCgo
+	case "unsafe":
+		write = writeUnsafe
+	case "top":
+		write = func(w io.Writer, g *graph) error {
+			return writeTop(w, g, *topCount)
+		}
+	case "testonly":
+		write = writeTestOnly
+	case "modgraph":
+		write = writeModGraph
+	case "explore":
+		write = func(w io.Writer, g *graph) error {
+			return explore(os.Stdin, w, g)
+		}
+	case "dominators":
+		write = writeDominators
+	case "impact":
+		asJSON := *outputFormat == "json"
+		write = func(w io.Writer, g *graph) error {
+			return writeImpact(w, g, asJSON)
+		}
+	case "stats":
+		asJSON := *outputFormat == "json"
+		write = func(w io.Writer, g *graph) error {
+			return writeStats(w, g, *statsSort, asJSON)
+		}
+	case "check":
+		// The violations are reported on stderr.
+		write = func(w io.Writer, g *graph) error {
+			return nil
 		}
 	}
 
-	fmt.Println("digraph godep {")
-	if *horizontal {
-		fmt.Println(`rankdir="LR"`)
+	cwd, err := os.Getwd()
+	if err != nil {
+		fatalf("failed to get cwd: %s", err)
+	}
+	stopProfiles, err := startProfiles(*cpuProfile, *memProfile)
+	if err != nil {
+		fatal(err)
+	}
+	defer stopProfiles()
+	if command == "diff" || diffBuild {
+		var differ bool
+		switch {
+		case diffBuild:
+			differ, err = diffBuilds(out, cwd, args, func(ctx *build.Context) {
+				if isFlagConfigured("diff-tags") {
+					ctx.BuildTags = diffTags
+				}
+				if *diffPlatform != "" {
+					setPlatform(ctx, diffGOOS, diffGOARCH)
+				}
+			})
+		case args == nil:
+			differ, err = diffSnapshots(out, diffRefs[0], diffRefs[1])
+		default:
+			differ, err = runDiff(out, cwd, diffRefs[0], diffRefs[1], args)
+		}
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			fatal(err)
+		}
+		if differ {
+			stopProfiles()
+			os.Exit(exitFailed)
+		}
+		return
 	}
 
-	// sort packages
-	pkgKeys := []string{}
-	for k := range pkgs {
-		pkgKeys = append(pkgKeys, k)
+	if *workspace {
+		mods, paths, err := workspacePackages(cwd)
+		if err != nil {
+			fatal(err)
+		}
+		workspaceModules = mods
+		args = append(args, paths...)
+	}
+	if command == "serve" {
+		if err := serve(*serveAddr, cwd, args); err != nil {
+			fatal(err)
+		}
+		return
 	}
-	sort.Strings(pkgKeys)
+	if *watch {
+		out.Close()
+		if err := watchPackages(cwd, args, write); err != nil {
+			fatal(err)
+		}
+		return
+	}
+	g, failed, err := run(cwd, args, out, write)
+	if err != nil {
+		fatal(err)
+	}
+	switch {
+	case len(g.brokenPackages()) > 0:
+		stopProfiles()
+		os.Exit(exitError)
+	case failed:
+		stopProfiles()
+		os.Exit(exitFailed)
+	}
+}
 
-	for _, pkgName := range pkgKeys {
-		pkg := pkgs[pkgName]
-		pkgId := getId(pkgName)
+// run scans the named packages, reports on them, and writes the graph to out,
+// which it closes. It returns the graph as scanned, and whether a check
+// failed.
+func run(cwd string, args []string, out io.WriteCloser, write func(io.Writer, *graph) error) (*graph, bool, error) {
+	defer out.Close()
+	stream = nil
+	if streamGraph && !*dryRun && !transforming() {
+		stream = json.NewEncoder(out)
+	}
+	runSummary = summary{}
+	start := time.Now()
+	scanned, err := scan(cwd, args)
+	if err != nil {
+		return nil, false, err
+	}
+	g := scanned
+	runSummary.Load = time.Since(start)
+	start = time.Now()
+	reportBroken(os.Stderr, g.brokenPackages())
+	reportCycles(infoWriter(), g)
+	testOnlyPackages = nil
+	if *includeTests {
+		testOnlyPackages = g.testOnly()
+	}
+	if isFlagConfigured("god-ca") {
+		gods := g.gods(*godCA, *godCE)
+		reportGods(infoWriter(), gods)
+		godPackages = make(map[string]bool)
+		for _, s := range gods {
+			godPackages[s.ImportPath] = true
+		}
+	}
+	if *writeBaselineFile != "" {
+		if err := writeBaseline(*writeBaselineFile, g); err != nil {
+			return nil, false, fmt.Errorf("failed to write baseline: %s", err)
+		}
+	}
+	if *findDuplicates {
+		dups := g.duplicates()
+		reportDuplicates(infoWriter(), dups)
+		duplicatedModules = duplicatedModuleSet(dups)
+	}
+	violations, err := checkPolicy(g)
+	if err != nil {
+		return nil, false, err
+	}
+	reportViolations(os.Stderr, violations)
+	exceeded := g.budgets()
+	reportBudgets(os.Stderr, exceeded)
+	if g, err = transform(g); err != nil {
+		return nil, false, err
+	}
+	runSummary.Analysis = time.Since(start)
+	start = time.Now()
+	if *dryRun {
+		err = writeDryRun(out, scanned, g)
+	} else {
+		err = write(out, g)
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to write graph: %s", err)
+	}
+	if err := out.Close(); err != nil {
+		return nil, false, fmt.Errorf("failed to write graph: %s", err)
+	}
+	runSummary.Output = time.Since(start)
+	if *showSummary {
+		runSummary.Filtered = len(scanned.Nodes) - len(g.Nodes)
+		runSummary.Nodes, runSummary.Edges = len(g.Nodes), len(g.edges())
+		reportSummary(os.Stderr, runSummary)
+	}
+	return scanned, len(violations) > 0 || len(exceeded) > 0, nil
+}
 
-		if isIgnored(pkg) {
-			continue
+// scan processes the named packages, resolving them relative to dir, and
+// returns the resulting graph.
+func scan(dir string, args []string) (*graph, error) {
+	pkgs = make(map[string]*packageInfo)
+	rootPkgs = nil
+	replacements, indirectModules = nil, make(map[string]bool)
+	localModules = workspaceUses(dir)
+	buildContext.Dir = dir
+	defer func() {
+		buildContext.Dir = dir
+	}()
+	targets, err := expandPatterns(dir, args)
+	if err != nil {
+		return nil, err
+	}
+	listedPackages, listedDirs = nil, nil
+	switch {
+	case *useGoList:
+		if err := listPackages(targets); err != nil {
+			return nil, err
+		}
+	case *incrementalFile != "":
+		cache = loadCache(*incrementalFile, true)
+	case *useCache:
+		if cache, err = openCache(); err != nil {
+			return nil, fmt.Errorf("failed to open cache: %s", err)
+		}
+	}
+	var queue []workItem
+	read := make(map[string]bool)
+	for _, t := range targets {
+		if !read[t.dir] {
+			read[t.dir] = true
+			readMainModule(t.dir)
 		}
+		queue = append(queue, workItem{srcDir: t.dir, importPath: t.importPath, contextDir: t.dir, level: 1})
+	}
+	if err := processPackages(queue); err != nil {
+		return nil, err
+	}
+	if cache != nil {
+		if err := cache.save(); err != nil {
+			return nil, fmt.Errorf("failed to save cache: %s", err)
+		}
+	}
+	return buildGraph(), nil
+}
+
+// transforming reports whether the graph is transformed once scanned, by
+// removing or merging some of its packages or imports.
+func transforming() bool {
+	return *without != "" || *pathsTo != "" || *reverse != "" || *focus != "" ||
+		*cyclesOnly || *condense || *granularity == "module" || *collapseStd ||
+		*collapseList != "" || *reduce
+}
 
-		var color string
-		if pkg.Goroot {
-			color = "palegreen"
-		} else if len(pkg.CgoFiles) > 0 {
-			color = "darkgoldenrod1"
+// transform applies the flags that select parts of the graph or annotate it.
+func transform(g *graph) (*graph, error) {
+	var err error
+	if *without != "" {
+		items := strings.Split(*without, ",")
+		var gone []string
+		if g, gone, err = g.without(items); err != nil {
+			return nil, err
+		}
+		reportWithout(infoWriter(), items, gone)
+	}
+	if *pathsTo != "" {
+		from := g.Roots
+		if *pathsFrom != "" {
+			from = []string{*pathsFrom}
+		}
+		if *shortestPath {
+			g, err = g.shortestPathGraph(from, *pathsTo)
 		} else {
-			color = "paleturquoise"
+			g, err = g.paths(from, *pathsTo)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if *reverse != "" {
+		if g, err = g.dependents(*reverse); err != nil {
+			return nil, err
+		}
+	}
+	if *focus != "" {
+		if g, err = g.neighborhood(*focus, *focusHops); err != nil {
+			return nil, err
+		}
+	}
+	if *cyclesOnly {
+		g = g.cyclesOnly()
+	}
+	if *condense {
+		g = g.condense()
+	}
+	if *granularity == "module" {
+		g = g.collapseModules()
+	}
+	if *collapseStd {
+		g = g.collapseStdlib()
+	}
+	if *collapseList != "" {
+		g = g.collapsePrefixes(strings.Split(*collapseList, ","))
+	}
+	if *reduce {
+		g = g.reduce()
+	}
+	if *countDeps {
+		transitiveCounts = make(map[string]int)
+		for _, n := range g.Nodes {
+			transitiveCounts[n.ImportPath] = g.transitiveCount(n.ImportPath)
+		}
+	}
+	if *cgoColors {
+		cgoTainted = make(map[string]bool)
+		for p := range g.chainsTo(isCgo) {
+			if !g.node(p).Cgo {
+				cgoTainted[p] = true
+			}
+		}
+	}
+	if *unsafeColors {
+		unsafeTainted = make(map[string]bool)
+		for p := range g.chainsTo(usesUnsafe) {
+			unsafeTainted[p] = true
+		}
+	}
+	if *distanceColors {
+		distances = make(map[string]float64)
+		for _, s := range g.stats() {
+			distances[s.ImportPath] = s.Distance
 		}
+	}
+	return g, nil
+}
 
-		fmt.Printf("_%d [label=\"%s\" style=\"filled\" color=\"%s\"];\n", pkgId, processName(pkgName), processColor(pkgName, color))
+// writeDot writes the graph in Graphviz dot format.
+func writeDot(w io.Writer, g *graph) error {
+	fmt.Fprintln(w, "digraph godep {")
+	if *horizontal {
+		fmt.Fprintln(w, `rankdir="LR"`)
+	}
 
-		// Don't render imports from packages in Goroot
-		if pkg.Goroot && !*delveGoroot {
-			continue
+	if *clusterModules {
+		writeDotClusters(w, g, func(n *node) string {
+			return n.Module
+		})
+	} else if workspaceModules != nil {
+		writeDotClusters(w, g, workspaceModuleOf)
+	}
+
+	cycles := g.cycles()
+	maxLines := 0
+	for _, n := range g.Nodes {
+		if n.Lines > maxLines {
+			maxLines = n.Lines
 		}
+	}
+	for _, n := range g.Nodes {
+		pkgId := getId(n.ImportPath)
 
-		for _, imp := range getImports(pkg) {
-			impPkg := pkgs[imp]
-			if impPkg == nil || isIgnored(impPkg) {
-				continue
+		label := processName(n.ImportPath)
+		if *showVersions && n.Version != "" {
+			label += "@" + n.Version
+		}
+		if count, ok := transitiveCounts[n.ImportPath]; ok {
+			label = fmt.Sprintf("%s (%d)", label, count)
+		}
+		if n.Replace != "" {
+			label += `\nreplaced by ` + n.Replace
+		}
+		style := "filled"
+		if testOnlyPackages[n.ImportPath] {
+			style = "dashed"
+		}
+		attrs := []string{dotAttr("label", label), dotAttr("style", style)}
+		if cycles.hasNode(n.ImportPath) {
+			attrs = append(attrs, dotAttr("fillcolor", nodeColor(n)), dotAttr("color", "red"), dotAttr("penwidth", "2"))
+		} else if n.Local {
+			attrs = append(attrs, dotAttr("fillcolor", nodeColor(n)), dotAttr("color", "darkviolet"), dotAttr("penwidth", "3"))
+		} else {
+			attrs = append(attrs, dotAttr("color", nodeColor(n)))
+		}
+		if n.Error != "" {
+			attrs = append(attrs, dotAttr("tooltip", dotEscaper.Replace(n.Error)), dotAttr("fontcolor", "white"))
+		} else if count, ok := transitiveCounts[n.ImportPath]; ok {
+			attrs = append(attrs, dotAttr("tooltip", fmt.Sprintf("%d transitive dependencies", count)))
+		} else if n.Version != "" {
+			attrs = append(attrs, dotAttr("tooltip", n.Module+"@"+n.Version))
+		}
+		if n.Module != "" && (duplicatedModules[n.Module] || duplicatedModules[withoutMajor(n.Module)]) {
+			attrs = append(attrs, dotAttr("peripheries", "2"))
+		}
+		if n.Indirect {
+			attrs = append(attrs, dotAttr("fontcolor", "dimgray"))
+		}
+		if godPackages[n.ImportPath] {
+			attrs = append(attrs, dotAttr("shape", "doubleoctagon"), dotAttr("fontcolor", "red"))
+		}
+		if *sizeByLines {
+			attrs = append(attrs, dotAttr("fontsize", fmt.Sprintf("%.1f", lineFontSize(n.Lines, maxLines))))
+		}
+		fmt.Fprintf(w, "_%d [%s];\n", pkgId, strings.Join(attrs, " "))
+
+		for _, imp := range n.Imports {
+			var attrs, styles []string
+			if forbidden[[2]string{n.ImportPath, imp}] {
+				attrs = append(attrs, dotAttr("color", "darkorange"))
+				styles = append(styles, "bold")
+			} else if cycles.hasEdge(n.ImportPath, imp) {
+				attrs = append(attrs, dotAttr("color", "red"))
+				styles = append(styles, "bold")
+			}
+			if style, ok := edgeStyles[n.importTypes[imp]]; ok {
+				styles = append(styles, style)
+			}
+			if len(styles) > 0 {
+				attrs = append(attrs, dotAttr("style", strings.Join(styles, ",")))
+			}
+			if *edgeWeights {
+				weight := n.importWeights[imp]
+				attrs = append(attrs,
+					dotAttr("label", strconv.Itoa(weight)),
+					dotAttr("penwidth", fmt.Sprintf("%.1f", edgePenWidth(weight))),
+					dotAttr("tooltip", fmt.Sprintf("imported by %d file(s)", weight)))
+			}
+			if len(attrs) > 0 {
+				fmt.Fprintf(w, "_%d -> _%d [%s];\n", pkgId, getId(imp), strings.Join(attrs, " "))
+			} else {
+				fmt.Fprintf(w, "_%d -> _%d;\n", pkgId, getId(imp))
 			}
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
 
-			impId := getId(imp)
-			fmt.Printf("_%d -> _%d;\n", pkgId, impId)
+// writeDotClusters groups the nodes for which clusterOf returns the same
+// non-empty name in a cluster labeled with that name.
+func writeDotClusters(w io.Writer, g *graph, clusterOf func(n *node) string) {
+	var names []string
+	members := make(map[string][]string)
+	for _, n := range g.Nodes {
+		name := clusterOf(n)
+		if name == "" {
+			continue
 		}
+		if _, ok := members[name]; !ok {
+			names = append(names, name)
+		}
+		members[name] = append(members[name], n.ImportPath)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		fmt.Fprintf(w, "subgraph cluster_%d {\n%s;\n", i, dotAttr("label", processName(name)))
+		for _, p := range members[name] {
+			fmt.Fprintf(w, "_%d;\n", getId(p))
+		}
+		fmt.Fprintln(w, "}")
 	}
-	fmt.Println("}")
+}
+
+// edgeStyles are the dot styles of the imports that only appear in the tests
+// or the external tests of a package.
+var edgeStyles = map[string]string{
+	edgeTest:  "dashed",
+	edgeXTest: "dotted",
+}
+
+// dotAttr formats a dot attribute.
+func dotAttr(name, value string) string {
+	return name + "=\"" + value + "\""
+}
+
+// dotEscaper escapes arbitrary text, such as an error message, to be used as
+// the value of a dot attribute.
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", " ")
+
+// nodeColor returns the fill color of a node, taking the -c color spec into
+// account.
+func nodeColor(n *node) string {
+	if n.Error != "" {
+		return "red"
+	}
+	if d, ok := distances[n.ImportPath]; ok {
+		return processColor(n.ImportPath, distanceColor(d))
+	}
+	var color string
+	if n.Goroot {
+		color = "palegreen"
+	} else if *replacedColors && n.Replace != "" {
+		color = "plum"
+	} else if unsafeTainted[n.ImportPath] && n.usesUnsafe {
+		color = "salmon"
+	} else if unsafeTainted[n.ImportPath] {
+		color = "mistyrose"
+	} else if n.Cgo {
+		color = "darkgoldenrod1"
+	} else if cgoTainted[n.ImportPath] {
+		color = "lightgoldenrod1"
+	} else {
+		color = "paleturquoise"
+	}
+	return processColor(n.ImportPath, color)
+}
+
+// Exit statuses, so that scripts can tell a failed check from an error.
+const (
+	// exitFailed means that a check found violations, or that the compared
+	// graphs differ.
+	exitFailed = 1
+	// exitError means that the command line is wrong, or that the packages
+	// or the graph couldn't be loaded or written.
+	exitError = 2
+)
+
+// fatal logs its arguments like log.Fatal, and exits with exitError.
+func fatal(v ...interface{}) {
+	log.Print(v...)
+	os.Exit(exitError)
+}
+
+// fatalf logs its arguments like log.Fatalf, and exits with exitError.
+func fatalf(format string, v ...interface{}) {
+	log.Printf(format, v...)
+	os.Exit(exitError)
+}
+
+// isFlagSet reports whether the named flag was given on the command line.
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// isFlagConfigured reports whether the named flag was given on the command
+// line, or set by an environment variable or the configuration file.
+func isFlagConfigured(name string) bool {
+	return isFlagSet(name) || presetFlags[name]
 }
 
 func processColor(name, color string) string {
@@ -177,60 +1083,152 @@ func processName(name string) string {
 	return newName
 }
 
-func processPackage(root string, pkgName string, level int) error {
-	if level++; level > *maxLevel {
-		return nil
-	}
-	if ignored[pkgName] {
-		return nil
-	}
+// workItem is a package waiting to be processed.
+type workItem struct {
+	// srcDir is the directory the import path is resolved from, the one of
+	// the importing package.
+	srcDir     string
+	importPath string
+	// contextDir is the directory the main module is found from, the one of
+	// the root package the package was reached from.
+	contextDir string
+	// level is the distance from the root packages, which are at level 1.
+	level int
+}
 
-	pkg, err := buildContext.Import(pkgName, root, 0)
-	if err != nil {
-		return fmt.Errorf("failed to import %s: %s", pkgName, err)
+// pendingImport is an import of a package that hasn't been processed yet.
+type pendingImport struct {
+	from *packageInfo
+	imp  packageImport
+}
+
+// processPackages processes the packages in the queue and the packages they
+// import, breadth first, so that the level of a package is its shortest
+// distance from the root packages.
+func processPackages(queue []workItem) error {
+	queued := make(map[string]bool)
+	// skipped holds why the packages that aren't part of the graph were
+	// skipped.
+	skipped := make(map[string]string)
+	for _, it := range queue {
+		queued[it.importPath] = true
 	}
+	// importers holds the imports of a package that hasn't been processed
+	// yet, which are streamed once it is.
+	importers := make(map[string][]pendingImport)
+	prog := newProgress(os.Stderr)
+	scanned, edges := 0, 0
+	for len(queue) > 0 {
+		if limit := exceededLimit(len(pkgs), edges); limit != "" {
+			warnTruncated(os.Stderr, limit, len(queue))
+			break
+		}
+		prog.update(scanned, len(queue))
+		it := queue[0]
+		queue = queue[1:]
+		scanned++
+		pkg, reason, err := processPackage(it)
+		if err != nil {
+			return err
+		}
+		if pkg == nil {
+			runSummary.Ignored++
+			if reason != "" {
+				skipped[it.importPath] = reason
+			}
+			continue
+		}
+		for _, p := range importers[it.importPath] {
+			edges++
+			if err := streamEdge(p.from, p.imp); err != nil {
+				return err
+			}
+		}
+		delete(importers, it.importPath)
 
-	if isIgnored(pkg) {
-		return nil
+		// Don't worry about dependencies for stdlib packages
+		if pkg.Goroot && !*delveGoroot {
+			continue
+		}
+		for _, i := range pkg.Imports {
+			imp := i.Path
+			if _, ok := pkgs[imp]; ok {
+				edges++
+				if err := streamEdge(pkg, i); err != nil {
+					return err
+				}
+				continue
+			}
+			importers[imp] = append(importers[imp], pendingImport{pkg, i})
+			if queued[imp] || skipped[imp] != "" {
+				continue
+			}
+			if it.level >= *maxLevel {
+				logf(levelDebug, "skipping %s, imported by %s: beyond -l %d", imp, pkg.ImportPath, *maxLevel)
+				skipped[imp] = "beyond -l"
+				runSummary.TooDeep++
+				continue
+			}
+			queued[imp] = true
+			queue = append(queue, workItem{srcDir: pkg.Dir, importPath: imp, contextDir: it.contextDir, level: it.level + 1})
+		}
+	}
+	for p, reason := range skipped {
+		runSummary.skip(reason, len(importers[p]))
 	}
+	runSummary.Scanned += scanned
+	prog.done(scanned)
+	return nil
+}
 
-	pkgs[normalizeVendor(pkg.ImportPath)] = pkg
+// processPackage imports a package and adds it to the processed packages. It
+// returns nil if the package is ignored, along with the reason, which is
+// empty for the C pseudo-package.
+func processPackage(it workItem) (*packageInfo, string, error) {
+	if it.importPath == "C" {
+		return nil, "", nil
+	}
+	if ignored[it.importPath] {
+		logf(levelDebug, "skipping %s: ignored by -i", it.importPath)
+		return nil, "ignored by -i", nil
+	}
 
-	// Don't worry about dependencies for stdlib packages
-	if pkg.Goroot && !*delveGoroot {
-		return nil
+	// In module mode, the main module is found from the build context's
+	// directory rather than from the directory of each import.
+	buildContext.Dir = it.contextDir
+	bpkg, err := importPackage(it.importPath, it.srcDir)
+	if err == nil && *modMode == "vendor" {
+		err = checkVendored(buildContext.Dir, bpkg.Dir, bpkg.Goroot)
+	}
+	var pkg *packageInfo
+	switch {
+	case err != nil && !*keepGoing:
+		return nil, "", fmt.Errorf("failed to import %s: %s", it.importPath, err)
+	case err != nil:
+		logf(levelVerbose, "failed to import %s: %s", it.importPath, err)
+		pkg = &packageInfo{ImportPath: normalizeVendor(it.importPath), Error: err.Error()}
+	default:
+		pkg = newPackageInfo(bpkg)
+	}
+	if reason := ignoreReason(pkg); reason != "" {
+		logf(levelDebug, "skipping %s: %s", pkg.ImportPath, reason)
+		return nil, reason, nil
 	}
 
-	for _, imp := range getImports(pkg) {
-		if _, ok := pkgs[imp]; !ok {
-			if err := processPackage(pkg.Dir, imp, level); err != nil {
-				return err
-			}
-		}
+	pkgs[pkg.ImportPath] = pkg
+	if it.level == 1 {
+		rootPkgs = append(rootPkgs, pkg.ImportPath)
 	}
-	return nil
+	return pkg, "", streamNode(pkg)
 }
 
-func getImports(pkg *build.Package) []string {
-	allImports := pkg.Imports
-	if *includeTests {
-		allImports = append(allImports, pkg.TestImports...)
-		allImports = append(allImports, pkg.XTestImports...)
-	}
-	var imports []string
-	found := make(map[string]struct{})
-	for _, imp := range allImports {
-		if imp == normalizeVendor(pkg.ImportPath) {
-			// Don't draw a self-reference when foo_test depends on foo.
-			continue
-		}
-		if _, ok := found[imp]; ok {
-			continue
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
 		}
-		found[imp] = struct{}{}
-		imports = append(imports, imp)
 	}
-	return imports
+	return false
 }
 
 func getId(name string) int {
@@ -252,19 +1250,58 @@ func hasPrefixes(s string, prefixes []string) bool {
 	return false
 }
 
-func isIgnored(pkg *build.Package) bool {
-	if len(onlyPrefixes) > 0 && !hasPrefixes(normalizeVendor(pkg.ImportPath), onlyPrefixes) {
-		return true
+func isIgnored(pkg *packageInfo) bool {
+	return ignoreReason(pkg) != ""
+}
+
+// ignoreReason returns why a package is ignored, naming the flag that
+// ignores it, or "" if it isn't.
+func ignoreReason(pkg *packageInfo) string {
+	switch {
+	case len(onlyPrefixes) > 0 && !hasPrefixes(pkg.ImportPath, onlyPrefixes):
+		return "not matched by -o"
+	case includeRegexp != nil && !includeRegexp.MatchString(pkg.ImportPath):
+		return "not matched by -include-re"
+	case excludeRegexp != nil && excludeRegexp.MatchString(pkg.ImportPath):
+		return "matched by -exclude-re"
+	case ignored[pkg.ImportPath]:
+		return "ignored by -i"
+	case pkg.Goroot && *ignoreStdlib:
+		return "ignored by -s"
+	case hasPrefixes(pkg.ImportPath, ignoredPrefixes):
+		return "matched by -p"
+	case matchGlobs(pkg.ImportPath, excludeGlobs):
+		return "matched by -x"
 	}
-	return ignored[normalizeVendor(pkg.ImportPath)] || (pkg.Goroot && *ignoreStdlib) || hasPrefixes(normalizeVendor(pkg.ImportPath), ignoredPrefixes)
+	return ""
 }
 
-func debug(args ...interface{}) {
-	fmt.Fprintln(os.Stderr, args...)
+// Verbosity levels of the messages written to stderr.
+const (
+	levelQuiet = iota - 1
+	levelInfo
+	levelVerbose
+	levelDebug
+)
+
+// verbosity is the level of the messages written to stderr, lowered by -q
+// and raised by -v and -vv.
+var verbosity = levelInfo
+
+// logf writes a message to stderr if the verbosity is at least level.
+func logf(level int, format string, args ...interface{}) {
+	if verbosity >= level {
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
+	}
 }
 
-func debugf(s string, args ...interface{}) {
-	fmt.Fprintf(os.Stderr, s, args...)
+// infoWriter returns where the reports that aren't checks, such as the
+// import cycles, are written: stderr, unless -q is given.
+func infoWriter() io.Writer {
+	if verbosity < levelInfo {
+		return ioutil.Discard
+	}
+	return os.Stderr
 }
 
 func normalizeVendor(path string) string {
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// writeMarkdown writes a Markdown table listing every package with the number
+// of packages it imports and is imported by, and its direct imports.
+func writeMarkdown(w io.Writer, g *graph) error {
+	importers := g.importers()
+
+	fmt.Fprintln(w, "| Package | Imports | Imported by | Direct imports |")
+	fmt.Fprintln(w, "| --- | ---: | ---: | --- |")
+	for _, n := range g.Nodes {
+		var imports []string
+		for _, imp := range n.Imports {
+			imports = append(imports, "`"+processName(imp)+"`")
+		}
+		if _, err := fmt.Fprintf(w, "| `%s` | %d | %d | %s |\n",
+			processName(n.ImportPath), len(n.Imports), len(importers[n.ImportPath]), strings.Join(imports, ", ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
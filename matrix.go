@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// writeMatrix writes the adjacency matrix of the graph as CSV. Each row lists
+// for one package the number of its files importing each of the packages in
+// the columns, or 0 if it doesn't import it.
+func writeMatrix(w io.Writer, g *graph) error {
+	index := make(map[string]int)
+	header := []string{""}
+	for i, n := range g.Nodes {
+		index[n.ImportPath] = i
+		header = append(header, processName(n.ImportPath))
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write(header)
+	for _, n := range g.Nodes {
+		row := make([]string, len(g.Nodes)+1)
+		row[0] = processName(n.ImportPath)
+		for i := range g.Nodes {
+			row[i+1] = "0"
+		}
+		for _, imp := range n.Imports {
+			row[index[imp]+1] = strconv.Itoa(n.importWeights[imp])
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
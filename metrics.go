@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+)
+
+// countLines returns the total number of lines of the given files in dir.
+// Files that can't be read are skipped.
+func countLines(dir string, files []string) int {
+	lines := 0
+	for _, name := range files {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		lines += bytes.Count(data, []byte("\n"))
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			lines++
+		}
+	}
+	return lines
+}
+
+// lineFontSize returns the font size of a node in the dot format with
+// -size-lines. It grows with the square root of the package's number of lines
+// so that its area is proportional to them, from 14 for an empty package up
+// to 56 for the largest one of the graph.
+func lineFontSize(lines, maxLines int) float64 {
+	if maxLines == 0 {
+		return 14
+	}
+	return 14 + 42*math.Sqrt(float64(lines)/float64(maxLines))
+}
+
+// abstractness returns the ratio of exported interface types to all exported
+// types declared in the non-test Go files of the node's package, or 0 if it
+// declares no exported types.
+func abstractness(n *node) float64 {
+	fset := token.NewFileSet()
+	types, interfaces := 0, 0
+	for _, name := range n.goFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(n.Dir, name), nil, 0)
+		if err != nil {
+			continue
+		}
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if !ts.Name.IsExported() {
+					continue
+				}
+				types++
+				if _, ok := ts.Type.(*ast.InterfaceType); ok {
+					interfaces++
+				}
+			}
+		}
+	}
+	if types == 0 {
+		return 0
+	}
+	return float64(interfaces) / float64(types)
+}
+
+// betweenness returns the betweenness centrality of every package: the sum,
+// over all the pairs of other packages, of the fraction of the shortest import
+// chains between them that go through the package. It uses Brandes'
+// algorithm.
+func (g *graph) betweenness() map[string]float64 {
+	centrality := make(map[string]float64)
+	for _, src := range g.Nodes {
+		var stack []string
+		preds := make(map[string][]string)
+		sigma := map[string]float64{src.ImportPath: 1}
+		dist := map[string]int{src.ImportPath: 0}
+		queue := []string{src.ImportPath}
+		for len(queue) > 0 {
+			p := queue[0]
+			queue = queue[1:]
+			stack = append(stack, p)
+			for _, imp := range g.node(p).Imports {
+				if _, ok := dist[imp]; !ok {
+					dist[imp] = dist[p] + 1
+					queue = append(queue, imp)
+				}
+				if dist[imp] == dist[p]+1 {
+					sigma[imp] += sigma[p]
+					preds[imp] = append(preds[imp], p)
+				}
+			}
+		}
+
+		delta := make(map[string]float64)
+		for i := len(stack) - 1; i >= 0; i-- {
+			p := stack[i]
+			for _, pred := range preds[p] {
+				delta[pred] += sigma[pred] / sigma[p] * (1 + delta[p])
+			}
+			if p != src.ImportPath {
+				centrality[p] += delta[p]
+			}
+		}
+	}
+	return centrality
+}
+
+// instability returns Ce/(Ca+Ce), or 0 for a package without any coupling.
+func instability(afferent, efferent int) float64 {
+	if afferent+efferent == 0 {
+		return 0
+	}
+	return float64(efferent) / float64(afferent+efferent)
+}
+
+// mainSequenceDistance returns how far a package is from the main sequence
+// A + I = 1, from 0 for a balanced package to 1 for a package that is either
+// abstract and stable or concrete and unstable.
+func mainSequenceDistance(abstractness, instability float64) float64 {
+	return math.Abs(abstractness + instability - 1)
+}
+
+// distances holds the main sequence distance of every package when nodes are
+// colored by it.
+var distances map[string]float64
+
+// distanceColor returns a color from green for a package on the main sequence
+// to red for a package at distance 1.
+func distanceColor(d float64) string {
+	lerp := func(a, b int) int {
+		return a + int(math.Round(float64(b-a)*d))
+	}
+	return fmt.Sprintf("#%02x%02x%02x", lerp(0x98, 0xff), lerp(0xfb, 0x60), lerp(0x98, 0x60))
+}
+
+// edgePenWidth returns the width of an import in the dot format with
+// -weights, from 1 for an import by a single file and growing with the
+// logarithm of the number of files.
+func edgePenWidth(weight int) float64 {
+	if weight < 1 {
+		return 1
+	}
+	return 1 + math.Log2(float64(weight))
+}
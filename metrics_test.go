@@ -0,0 +1,45 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBetweenness(t *testing.T) {
+	tests := []struct {
+		name    string
+		imports map[string][]string
+		want    map[string]float64
+	}{
+		{
+			name:    "chain",
+			imports: map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"d"}},
+			want:    map[string]float64{"b": 2, "c": 2},
+		},
+		{
+			name: "diamond",
+			imports: map[string][]string{
+				"a": {"b", "c"},
+				"b": {"d"},
+				"c": {"d"},
+			},
+			want: map[string]float64{"b": 0.5, "c": 0.5},
+		},
+		{
+			name:    "shortcut",
+			imports: map[string][]string{"a": {"b", "c"}, "b": {"c"}},
+			want:    map[string]float64{},
+		},
+	}
+	for _, tt := range tests {
+		got := testGraph([]string{"a"}, tt.imports).betweenness()
+		for p, c := range got {
+			if c == 0 {
+				delete(got, p)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: betweenness() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// goModGraph runs go mod graph in dir and returns its requirements between
+// module paths, ignoring their versions and the go and toolchain
+// requirements.
+func goModGraph(dir string) (map[[2]string]bool, error) {
+	cmd := exec.Command("go", "mod", "graph")
+	cmd.Dir = dir
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go mod graph failed: %s", err)
+	}
+	reqs := make(map[[2]string]bool)
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		from, to := modulePathOf(fields[0]), modulePathOf(fields[1])
+		if to == "go" || to == "toolchain" {
+			continue
+		}
+		reqs[[2]string{from, to}] = true
+	}
+	return reqs, nil
+}
+
+// modulePathOf strips the version from a module given as path@version.
+func modulePathOf(mod string) string {
+	if at := strings.LastIndex(mod, "@"); at >= 0 {
+		return mod[:at]
+	}
+	return mod
+}
+
+// moduleImports returns the imports between the modules of the graph,
+// leaving out the standard library and the packages outside of any module.
+func (g *graph) moduleImports() map[[2]string]bool {
+	imports := make(map[[2]string]bool)
+	for _, e := range g.edges() {
+		from, to := g.node(e.From), g.node(e.To)
+		if from.Module == "" || to.Module == "" || from.Module == to.Module {
+			continue
+		}
+		imports[[2]string{from.Module, to.Module}] = true
+	}
+	return imports
+}
+
+// writeModGraph writes the graph of the modules of the graph, overlaid with
+// the requirements of go mod graph run in the current directory. Requirements
+// that aren't backed by an import are dashed, and imports without a
+// requirement are red. The requirements of the main module that it doesn't
+// need, and its imports of modules it doesn't require, are listed on stderr.
+func writeModGraph(w io.Writer, g *graph) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	m := moduleOf(cwd)
+	if m == nil {
+		return fmt.Errorf("modgraph must be run in a module")
+	}
+	reqs, err := goModGraph(cwd)
+	if err != nil {
+		return err
+	}
+	imports := g.moduleImports()
+
+	present := make(map[string]bool)
+	for _, n := range g.Nodes {
+		if n.Module != "" {
+			present[n.Module] = true
+		}
+	}
+	var unused, unrequired []string
+	edges := make(map[[2]string]bool)
+	for e := range reqs {
+		switch {
+		case e[0] == m.Path && !present[e[1]]:
+			unused = append(unused, e[1])
+			edges[e] = true
+		case present[e[0]] && present[e[1]]:
+			edges[e] = true
+		}
+	}
+	for e := range imports {
+		edges[e] = true
+		if e[0] == m.Path && !reqs[e] {
+			unrequired = append(unrequired, e[1])
+		}
+	}
+	sort.Strings(unused)
+	sort.Strings(unrequired)
+	reportModGraph(os.Stderr, unused, unrequired)
+
+	var sorted [][2]string
+	for e := range edges {
+		sorted = append(sorted, e)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i][0] != sorted[j][0] {
+			return sorted[i][0] < sorted[j][0]
+		}
+		return sorted[i][1] < sorted[j][1]
+	})
+
+	fmt.Fprintln(w, "digraph godep {")
+	if *horizontal {
+		fmt.Fprintln(w, `rankdir="LR"`)
+	}
+	var mods []string
+	for p := range present {
+		mods = append(mods, p)
+	}
+	sort.Strings(mods)
+	for _, p := range mods {
+		fmt.Fprintf(w, "_%d [%s %s %s];\n", getId(p), dotAttr("label", processName(p)), dotAttr("style", "filled"), dotAttr("color", processColor(p, "paleturquoise")))
+	}
+	for _, p := range unused {
+		fmt.Fprintf(w, "_%d [%s %s %s];\n", getId(p), dotAttr("label", processName(p)), dotAttr("style", "dashed"), dotAttr("color", "gray"))
+	}
+	for _, e := range sorted {
+		switch {
+		case !imports[e]:
+			fmt.Fprintf(w, "_%d -> _%d [%s %s];\n", getId(e[0]), getId(e[1]), dotAttr("style", "dashed"), dotAttr("color", "gray"))
+		case !reqs[e]:
+			fmt.Fprintf(w, "_%d -> _%d [%s %s];\n", getId(e[0]), getId(e[1]), dotAttr("style", "bold"), dotAttr("color", "red"))
+		default:
+			fmt.Fprintf(w, "_%d -> _%d;\n", getId(e[0]), getId(e[1]))
+		}
+	}
+	_, err = fmt.Fprintln(w, "}")
+	return err
+}
+
+// reportModGraph writes a summary of the requirements of the main module
+// that aren't imported, and of the modules it imports without requiring them.
+func reportModGraph(w io.Writer, unused, unrequired []string) {
+	if len(unused) > 0 {
+		fmt.Fprintf(w, "found %d required module(s) that are never imported:\n", len(unused))
+		for _, p := range unused {
+			fmt.Fprintf(w, "  %s\n", processName(p))
+		}
+	}
+	if len(unrequired) > 0 {
+		fmt.Fprintf(w, "found %d imported module(s) that aren't required:\n", len(unrequired))
+		for _, p := range unrequired {
+			fmt.Fprintf(w, "  %s\n", processName(p))
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// module describes the Go module a package belongs to.
+type module struct {
+	Path    string
+	Version string
+	Dir     string
+}
+
+// modules caches the result of moduleOf by package directory.
+var modules = make(map[string]*module)
+
+// moduleOf returns the module containing the package in dir, or nil if the
+// package isn't part of a module. Modules in the module cache get their
+// version from the cache directory name, and vendored modules from the
+// vendor/modules.txt file; other modules are found by looking for a go.mod
+// file in dir and its parents and have no version.
+func moduleOf(dir string) *module {
+	if dir == "" {
+		return nil
+	}
+	if m, ok := modules[dir]; ok {
+		return m
+	}
+	m := findModule(dir)
+	modules[dir] = m
+	return m
+}
+
+func findModule(dir string) *module {
+	if cache := modCacheDir(); cache != "" {
+		if rel, err := filepath.Rel(cache, dir); err == nil && !strings.HasPrefix(rel, "..") {
+			parts := strings.Split(filepath.ToSlash(rel), "/")
+			for i, p := range parts {
+				if at := strings.LastIndex(p, "@"); at >= 0 {
+					path := strings.Join(append(parts[:i:i], p[:at]), "/")
+					return &module{
+						Path:    unescapeModulePath(path),
+						Version: p[at+1:],
+						Dir:     filepath.Join(cache, filepath.FromSlash(strings.Join(parts[:i+1], "/"))),
+					}
+				}
+			}
+		}
+	}
+
+	if m := vendoredModule(dir); m != nil {
+		return m
+	}
+
+	for d := dir; ; {
+		if path := modulePath(filepath.Join(d, "go.mod")); path != "" {
+			return &module{Path: path, Dir: d}
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return nil
+		}
+		d = parent
+	}
+}
+
+// vendoredModule returns the module containing the package in dir if it is
+// in a vendor directory listing its modules in a modules.txt file, choosing
+// the longest module path matching the package's path in the directory.
+func vendoredModule(dir string) *module {
+	slashed := filepath.ToSlash(dir)
+	i := strings.LastIndex(slashed, "/vendor/")
+	if i < 0 {
+		return nil
+	}
+	vendor := filepath.FromSlash(slashed[:i+len("/vendor")])
+	path := slashed[i+len("/vendor/"):]
+
+	f, err := os.Open(filepath.Join(vendor, "modules.txt"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var found *module
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 3 || fields[0] != "#" {
+			continue
+		}
+		mod := fields[1]
+		if (path == mod || strings.HasPrefix(path, mod+"/")) && (found == nil || len(mod) > len(found.Path)) {
+			found = &module{
+				Path:    mod,
+				Version: fields[2],
+				Dir:     filepath.Join(vendor, filepath.FromSlash(mod)),
+			}
+		}
+	}
+	return found
+}
+
+// modCacheDir returns the directory of the module cache.
+func modCacheDir() string {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir
+	}
+	gopath := filepath.SplitList(buildContext.GOPATH)
+	if len(gopath) == 0 || gopath[0] == "" {
+		return ""
+	}
+	return filepath.Join(gopath[0], "pkg", "mod")
+}
+
+// modulePath returns the module path declared in the named go.mod file, or ""
+// if the file doesn't exist or has no module directive.
+func modulePath(gomod string) string {
+	f, err := os.Open(gomod)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if strings.HasPrefix(line, "module") {
+			path := strings.TrimSpace(strings.TrimPrefix(line, "module"))
+			if i := strings.Index(path, "//"); i >= 0 {
+				path = strings.TrimSpace(path[:i])
+			}
+			return strings.Trim(path, `"`)
+		}
+	}
+	return ""
+}
+
+// unescapeModulePath reverses the case encoding used for module paths in the
+// module cache, where upper case letters are written as "!" followed by the
+// lower case letter.
+func unescapeModulePath(path string) string {
+	var b strings.Builder
+	bang := false
+	for _, r := range path {
+		if bang {
+			b.WriteString(strings.ToUpper(string(r)))
+			bang = false
+		} else if r == '!' {
+			bang = true
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
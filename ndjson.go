@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// stream is set when the output format is ndjson and the graph isn't
+// transformed once scanned, in which case nodes and edges are written as soon
+// as they are discovered.
+var stream *json.Encoder
+
+// streamGraph is set when the graph itself is written in the ndjson format,
+// rather than the output of a command.
+var streamGraph bool
+
+type ndjsonRecord struct {
+	Kind string `json:"kind"`
+	*node
+	*edge
+}
+
+// streamNode writes the node for pkg to the stream, if there is one.
+func streamNode(pkg *packageInfo) error {
+	if stream == nil {
+		return nil
+	}
+	return stream.Encode(ndjsonRecord{Kind: "node", node: newNode(pkg)})
+}
+
+// streamEdge writes the import of imp by pkg to the stream, if there is one
+// and the imported package is part of the graph.
+func streamEdge(pkg *packageInfo, imp packageImport) error {
+	if stream == nil {
+		return nil
+	}
+	impPkg := pkgs[imp.Path]
+	if impPkg == nil || isIgnored(impPkg) {
+		return nil
+	}
+	return stream.Encode(ndjsonRecord{Kind: "edge", edge: &edge{
+		From:   pkg.ImportPath,
+		To:     imp.Path,
+		Type:   imp.Type,
+		Weight: imp.Weight,
+	}})
+}
+
+// writeNDJSON writes the nodes of the graph followed by its edges, unless
+// the graph was already streamed while the packages were processed.
+func writeNDJSON(w io.Writer, g *graph) error {
+	if stream != nil {
+		return nil
+	}
+	enc := json.NewEncoder(w)
+	for _, n := range g.Nodes {
+		if err := enc.Encode(ndjsonRecord{Kind: "node", node: n}); err != nil {
+			return err
+		}
+	}
+	for _, e := range g.edges() {
+		e := e
+		if err := enc.Encode(ndjsonRecord{Kind: "edge", edge: &e}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
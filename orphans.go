@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"go/build"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// modulePackages returns the packages found on disk in the module containing
+// root, or in the directory tree of root if it isn't part of a module.
+// Directories of nested modules, vendor and testdata directories, and those
+// ignored by the go tool are skipped.
+func modulePackages(root *build.Package) ([]*build.Package, error) {
+	dir, importPath := root.Dir, normalizeVendor(root.ImportPath)
+	if m := moduleOf(root.Dir); m != nil && m.Version == "" {
+		dir, importPath = m.Dir, m.Path
+	}
+	return treePackages(dir, importPath)
+}
+
+// treePackages returns the packages found on disk in the directory tree of
+// dir, whose import path is importPath, skipping the same directories as
+// modulePackages.
+func treePackages(dir, importPath string) ([]*build.Package, error) {
+	var found []*build.Package
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		name := info.Name()
+		if p != dir {
+			if name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(p, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+		}
+		pkg, err := buildContext.ImportDir(p, 0)
+		if err != nil {
+			if _, ok := err.(*build.NoGoError); ok {
+				return nil
+			}
+			return fmt.Errorf("failed to import %s: %s", p, err)
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		pkg.ImportPath = path.Join(importPath, filepath.ToSlash(rel))
+		found = append(found, pkg)
+		return nil
+	})
+	return found, err
+}
+
+// writeOrphans writes the import paths of the packages of the module containing
+// the named package that no other package of the module imports, leaving out
+// main packages.
+func writeOrphans(w io.Writer, pkgName string) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	root, err := buildContext.Import(pkgName, cwd, build.FindOnly)
+	if err != nil {
+		return fmt.Errorf("failed to import %s: %s", pkgName, err)
+	}
+	found, err := modulePackages(root)
+	if err != nil {
+		return err
+	}
+
+	imported := make(map[string]bool)
+	for _, pkg := range found {
+		for _, imports := range [][]string{pkg.Imports, pkg.TestImports, pkg.XTestImports} {
+			for _, imp := range imports {
+				if imp != pkg.ImportPath {
+					imported[normalizeVendor(imp)] = true
+				}
+			}
+		}
+	}
+
+	var orphans []string
+	for _, pkg := range found {
+		if pkg.Name != "main" && !imported[pkg.ImportPath] {
+			orphans = append(orphans, pkg.ImportPath)
+		}
+	}
+	sort.Strings(orphans)
+	for _, o := range orphans {
+		if _, err := fmt.Fprintln(w, o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// renderFormats are the image formats that can be passed to -T.
+var renderFormats = map[string]bool{
+	"svg": true,
+	"png": true,
+	"pdf": true,
+}
+
+// fileFormats maps output file extensions to output formats.
+var fileFormats = map[string]string{
+	".dot":        "dot",
+	".gv":         "dot",
+	".json":       "json",
+	".ndjson":     "ndjson",
+	".yaml":       "yaml",
+	".yml":        "yaml",
+	".graphml":    "graphml",
+	".d2":         "d2",
+	".csv":        "csv",
+	".tgf":        "tgf",
+	".gexf":       "gexf",
+	".html":       "html",
+	".htm":        "html",
+	".txt":        "tree",
+	".md":         "markdown",
+	".tex":        "tikz",
+	".cypher":     "cypher",
+	".sql":        "sql",
+	".db":         "sqlite",
+	".sqlite":     "sqlite",
+	".pb":         "proto",
+	".excalidraw": "excalidraw",
+}
+
+// formatForFile guesses the output format and render format from the
+// extension of an output file name. Unknown extensions give the dot format.
+func formatForFile(name string) (format, render string) {
+	ext := strings.ToLower(filepath.Ext(name))
+	if renderFormats[strings.TrimPrefix(ext, ".")] {
+		return "dot", strings.TrimPrefix(ext, ".")
+	}
+	if format, ok := fileFormats[ext]; ok {
+		return format, ""
+	}
+	return "dot", ""
+}
+
+// createOutput opens the named file for writing the output, or returns stdout
+// if name is empty.
+func createOutput(name string) (io.WriteCloser, error) {
+	if name == "" {
+		return nopCloser{os.Stdout}, nil
+	}
+	return os.Create(name)
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// renderDot pipes the graph in dot format through the Graphviz dot binary and
+// writes the resulting image in the given format to w.
+func renderDot(w io.Writer, g *graph, dot, format string) error {
+	var buf bytes.Buffer
+	if err := writeDot(&buf, g); err != nil {
+		return err
+	}
+
+	cmd := exec.Command(dot, "-T"+format)
+	cmd.Stdin = &buf
+	cmd.Stdout = w
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("dot failed: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"go/build"
+	"go/token"
+)
+
+// packageInfo is the part of a package resolved by go/build that the graph
+// needs. Only these are kept for each processed package, rather than the whole
+// build.Package with all its file lists and import positions, which takes
+// much more memory on repositories with tens of thousands of packages.
+type packageInfo struct {
+	// ImportPath is the import path of the package without any vendor
+	// directory prefix, which Vendored tells.
+	ImportPath string
+	Vendored   bool
+	Dir        string
+	Goroot     bool
+	Cgo        bool
+	// GoFiles lists the non-test Go files of the package, relative to Dir.
+	GoFiles    []string
+	UsesUnsafe bool
+	// Error is why the package couldn't be imported, with -k.
+	Error string
+	// Lines is the number of lines of the Go files of the package, only
+	// counted with -size-lines.
+	Lines int
+	// Imports lists the imports of the package, followed with -t by those of
+	// its tests, without duplicates.
+	Imports []packageImport
+}
+
+// packageImport is an import of a package.
+type packageImport struct {
+	Path string
+	// Type is one of the edge types.
+	Type string
+	// Weight is the number of files importing the package.
+	Weight int
+	// Pos is the position of the first import statement.
+	Pos token.Position
+}
+
+// newPackageInfo returns the part of pkg that the graph needs.
+func newPackageInfo(pkg *build.Package) *packageInfo {
+	p := &packageInfo{
+		ImportPath: normalizeVendor(pkg.ImportPath),
+		Dir:        pkg.Dir,
+		Goroot:     pkg.Goroot,
+		Cgo:        len(pkg.CgoFiles) > 0,
+		GoFiles:    pkg.GoFiles,
+		UsesUnsafe: contains(pkg.Imports, "unsafe"),
+	}
+	p.Vendored = pkg.ImportPath != p.ImportPath
+	if *sizeByLines {
+		p.Lines = countLines(pkg.Dir, append(pkg.GoFiles, pkg.CgoFiles...))
+	}
+	for _, imp := range getImports(pkg) {
+		p.Imports = append(p.Imports, packageImport{
+			Path:   imp,
+			Type:   importType(pkg, imp),
+			Weight: importWeight(pkg, imp),
+			Pos:    importPos(pkg, imp),
+		})
+	}
+	return p
+}
+
+func getImports(pkg *build.Package) []string {
+	allImports := pkg.Imports
+	if *includeTests {
+		allImports = append(allImports, pkg.TestImports...)
+		allImports = append(allImports, pkg.XTestImports...)
+	}
+	var imports []string
+	found := make(map[string]struct{})
+	for _, imp := range allImports {
+		if imp == normalizeVendor(pkg.ImportPath) {
+			// Don't draw a self-reference when foo_test depends on foo.
+			continue
+		}
+		if _, ok := found[imp]; ok {
+			continue
+		}
+		found[imp] = struct{}{}
+		imports = append(imports, imp)
+	}
+	return imports
+}
+
+// importType reports whether imp is imported by the package itself, by its
+// tests or only by its external tests.
+func importType(pkg *build.Package, imp string) string {
+	if contains(pkg.Imports, imp) {
+		return edgeImport
+	}
+	if contains(pkg.TestImports, imp) {
+		return edgeTest
+	}
+	return edgeXTest
+}
+
+// importWeight returns the number of files of pkg that import imp.
+func importWeight(pkg *build.Package, imp string) int {
+	positions := pkg.ImportPos[imp]
+	if *includeTests {
+		positions = append(positions, pkg.TestImportPos[imp]...)
+		positions = append(positions, pkg.XTestImportPos[imp]...)
+	}
+	files := make(map[string]bool)
+	for _, pos := range positions {
+		files[pos.Filename] = true
+	}
+	if len(files) == 0 {
+		return 1
+	}
+	return len(files)
+}
+
+// importPos returns the position of the first statement of pkg importing imp.
+func importPos(pkg *build.Package, imp string) token.Position {
+	for _, positions := range [][]token.Position{pkg.ImportPos[imp], pkg.TestImportPos[imp], pkg.XTestImportPos[imp]} {
+		if len(positions) > 0 {
+			return positions[0]
+		}
+	}
+	return token.Position{}
+}
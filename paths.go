@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// reachable returns the packages that can be reached from the given packages
+// by following imports, including the packages themselves.
+func (g *graph) reachable(from []string) map[string]bool {
+	return g.reachableExcept(from, nil)
+}
+
+// reachableExcept is like reachable, but doesn't follow the imports for which
+// skip returns true.
+func (g *graph) reachableExcept(from []string, skip func(from, to string) bool) map[string]bool {
+	seen := make(map[string]bool)
+	queue := append([]string(nil), from...)
+	for _, p := range from {
+		seen[p] = true
+	}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, imp := range g.node(p).Imports {
+			if !seen[imp] && (skip == nil || !skip(p, imp)) {
+				seen[imp] = true
+				queue = append(queue, imp)
+			}
+		}
+	}
+	return seen
+}
+
+// testOnlyPackages holds the packages of the scanned graph that are only
+// reachable from the root packages through the imports of test files, when
+// -t is given, which are drawn dashed in the dot format.
+var testOnlyPackages map[string]bool
+
+// testOnly returns the packages that are only reachable from the root
+// packages through the imports of test files.
+func (g *graph) testOnly() map[string]bool {
+	prod := g.reachableExcept(g.Roots, func(from, to string) bool {
+		return g.node(from).importTypes[to] != edgeImport
+	})
+	testOnly := make(map[string]bool)
+	for _, n := range g.Nodes {
+		if !prod[n.ImportPath] {
+			testOnly[n.ImportPath] = true
+		}
+	}
+	return testOnly
+}
+
+// writeTestOnly writes the packages that are only reachable from the root
+// packages through the imports of test files, with their module when known.
+func writeTestOnly(w io.Writer, g *graph) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "PACKAGE\tMODULE")
+	for _, n := range g.Nodes {
+		if !testOnlyPackages[n.ImportPath] {
+			continue
+		}
+		mod := n.Module
+		if n.Version != "" {
+			mod += "@" + n.Version
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", processName(n.ImportPath), mod)
+	}
+	return tw.Flush()
+}
+
+// transitiveCount returns the number of packages the given package depends
+// on, directly or indirectly.
+func (g *graph) transitiveCount(p string) int {
+	return len(g.reachable([]string{p})) - 1
+}
+
+// reaching returns the packages from which one of the given packages can be
+// reached by following imports, including the packages themselves.
+func (g *graph) reaching(to []string) map[string]bool {
+	importers := g.importers()
+	seen := make(map[string]bool)
+	queue := append([]string(nil), to...)
+	for _, p := range to {
+		seen[p] = true
+	}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, imp := range importers[p] {
+			if !seen[imp] {
+				seen[imp] = true
+				queue = append(queue, imp)
+			}
+		}
+	}
+	return seen
+}
+
+// paths returns the part of the graph made of the packages and imports that
+// are on an import path from one of the from packages to the to package.
+func (g *graph) paths(from []string, to string) (*graph, error) {
+	if err := g.checkNodes(append([]string{to}, from...)); err != nil {
+		return nil, err
+	}
+	forward := g.reachable(from)
+	backward := g.reaching([]string{to})
+	return g.subgraph(func(n *node) bool {
+		return forward[n.ImportPath] && backward[n.ImportPath]
+	}, nil), nil
+}
+
+// shortestPath returns the shortest import chain from one of the from
+// packages to the to package, or nil if there is none.
+func (g *graph) shortestPath(from []string, to string) []string {
+	prev := make(map[string]string)
+	seen := make(map[string]bool)
+	queue := append([]string(nil), from...)
+	for _, p := range from {
+		seen[p] = true
+	}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		if p == to {
+			path := []string{to}
+			for q := to; !contains(from, q); q = prev[q] {
+				path = append([]string{prev[q]}, path...)
+			}
+			return path
+		}
+		for _, imp := range g.node(p).Imports {
+			if !seen[imp] {
+				seen[imp] = true
+				prev[imp] = p
+				queue = append(queue, imp)
+			}
+		}
+	}
+	return nil
+}
+
+// shortestPathGraph returns the part of the graph made of the shortest import
+// chain from one of the from packages to the to package.
+func (g *graph) shortestPathGraph(from []string, to string) (*graph, error) {
+	if err := g.checkNodes(append([]string{to}, from...)); err != nil {
+		return nil, err
+	}
+	path := g.shortestPath(from, to)
+	if path == nil {
+		return nil, fmt.Errorf("no import path to %s", to)
+	}
+	next := make(map[string]string)
+	for i := 0; i < len(path)-1; i++ {
+		next[path[i]] = path[i+1]
+	}
+	return g.subgraph(func(n *node) bool {
+		_, ok := next[n.ImportPath]
+		return ok || n.ImportPath == to
+	}, func(from, to string) bool {
+		return next[from] == to
+	}), nil
+}
+
+// checkNodes returns an error if one of the packages isn't part of the graph.
+func (g *graph) checkNodes(paths []string) error {
+	for _, p := range paths {
+		if g.node(p) == nil {
+			return fmt.Errorf("package %s is not part of the graph", p)
+		}
+	}
+	return nil
+}
+
+// dependents returns the part of the graph made of the packages that import
+// the given package, directly or indirectly.
+func (g *graph) dependents(p string) (*graph, error) {
+	if err := g.checkNodes([]string{p}); err != nil {
+		return nil, err
+	}
+	backward := g.reaching([]string{p})
+	return g.subgraph(func(n *node) bool {
+		return backward[n.ImportPath]
+	}, nil), nil
+}
+
+// neighborhood returns the part of the graph made of the packages that are at
+// most hops imports away from p, in either direction.
+func (g *graph) neighborhood(p string, hops int) (*graph, error) {
+	if err := g.checkNodes([]string{p}); err != nil {
+		return nil, err
+	}
+	importers := g.importers()
+	dist := map[string]int{p: 0}
+	queue := []string{p}
+	for len(queue) > 0 {
+		q := queue[0]
+		queue = queue[1:]
+		if dist[q] == hops {
+			continue
+		}
+		for _, next := range append(append([]string(nil), g.node(q).Imports...), importers[q]...) {
+			if _, ok := dist[next]; !ok {
+				dist[next] = dist[q] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+	return g.subgraph(func(n *node) bool {
+		_, ok := dist[n.ImportPath]
+		return ok
+	}, nil), nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"go/build"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// readStdinArgs replaces a - argument with the patterns read from r, one per
+// line, so that the packages can be piped from go list or another command.
+func readStdinArgs(args []string, r io.Reader) ([]string, error) {
+	var expanded []string
+	read := false
+	for _, arg := range args {
+		if arg != "-" {
+			expanded = append(expanded, arg)
+			continue
+		}
+		if read {
+			return nil, fmt.Errorf("- can only be given once")
+		}
+		read = true
+		s := bufio.NewScanner(r)
+		for s.Scan() {
+			if line := strings.TrimSpace(s.Text()); line != "" {
+				expanded = append(expanded, line)
+			}
+		}
+		if err := s.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read packages from stdin: %s", err)
+		}
+	}
+	return expanded, nil
+}
+
+// target is a package given on the command line, with the directory its
+// imports are resolved from.
+type target struct {
+	importPath string
+	// dir is the directory of the module containing the package when it was
+	// given as a directory, so that the packages of several modules can be
+	// scanned together, and the directory godepgraph runs in otherwise.
+	dir string
+}
+
+// expandPatterns turns the packages given on the command line into import
+// paths, like the go command does. Relative or absolute directories such as
+// ./cmd/bar are resolved relative to dir, and a pattern ending in /... such
+// as ./... or github.com/foo/bar/... is replaced by all the packages found in
+// the directory tree it names. With -nested, the packages of the modules
+// nested in the directory tree of a relative pattern are included as well,
+// even if the tree itself isn't part of a module, such as the root of a
+// repository holding several modules.
+func expandPatterns(dir string, args []string) ([]target, error) {
+	var targets []target
+	for _, arg := range args {
+		base, recursive := arg, false
+		if arg == "..." || strings.HasSuffix(arg, "/...") {
+			base, recursive = strings.TrimSuffix(strings.TrimSuffix(arg, "..."), "/"), true
+			if base == "" {
+				return nil, fmt.Errorf("pattern %s is not supported, use ./... or a package path followed by /...", arg)
+			}
+		}
+
+		var pkgDir, importPath string
+		local := build.IsLocalImport(base) || filepath.IsAbs(base)
+		if local {
+			pkgDir = base
+			if !filepath.IsAbs(base) {
+				pkgDir = filepath.Join(dir, filepath.FromSlash(base))
+			}
+			p, err := dirImportPath(pkgDir)
+			if err != nil && !(recursive && *scanNested) {
+				return nil, err
+			}
+			importPath = p
+		} else {
+			importPath = base
+			if recursive {
+				pkg, err := buildContext.Import(base, dir, build.FindOnly)
+				if err != nil {
+					return nil, fmt.Errorf("failed to import %s: %s", base, err)
+				}
+				pkgDir = pkg.Dir
+			}
+		}
+		if !recursive {
+			targets = append(targets, target{importPath, resolveDir(dir, pkgDir, local)})
+			continue
+		}
+
+		var trees []target
+		if importPath != "" {
+			trees = append(trees, target{importPath, pkgDir})
+		}
+		if local && *scanNested {
+			nested, err := nestedModules(pkgDir)
+			if err != nil {
+				return nil, err
+			}
+			trees = append(trees, nested...)
+		}
+		found := 0
+		for _, t := range trees {
+			pkgs, err := treePackages(t.dir, t.importPath)
+			if err != nil {
+				return nil, err
+			}
+			for _, pkg := range pkgs {
+				targets = append(targets, target{pkg.ImportPath, resolveDir(dir, t.dir, local)})
+			}
+			found += len(pkgs)
+		}
+		if found == 0 {
+			return nil, fmt.Errorf("pattern %s matched no packages", arg)
+		}
+	}
+	return targets, nil
+}
+
+// resolveDir returns the directory the imports of a package in pkgDir are
+// resolved from: the directory of its module if it was given as a
+// directory, and dir otherwise.
+func resolveDir(dir, pkgDir string, local bool) string {
+	if !local {
+		return dir
+	}
+	if m := moduleOf(pkgDir); m != nil && m.Version == "" {
+		return m.Dir
+	}
+	return dir
+}
+
+// nestedModules returns the modules whose directories are nested in the tree
+// of dir, as targets naming their module path and directory.
+func nestedModules(dir string) ([]target, error) {
+	var nested []target
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() || p == dir {
+			return nil
+		}
+		name := info.Name()
+		if name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+			return filepath.SkipDir
+		}
+		if path := modulePath(filepath.Join(p, "go.mod")); path != "" {
+			nested = append(nested, target{path, p})
+		}
+		return nil
+	})
+	return nested, err
+}
+
+// dirImportPath returns the import path of the package in dir, from the
+// module containing it or from its location in GOPATH.
+func dirImportPath(dir string) (string, error) {
+	if m := moduleOf(dir); m != nil && m.Version == "" {
+		rel, err := filepath.Rel(m.Dir, dir)
+		if err != nil {
+			return "", err
+		}
+		return path.Join(m.Path, filepath.ToSlash(rel)), nil
+	}
+	pkg, err := buildContext.ImportDir(dir, build.FindOnly)
+	if err != nil {
+		return "", fmt.Errorf("failed to import %s: %s", dir, err)
+	}
+	if pkg.ImportPath == "" || build.IsLocalImport(pkg.ImportPath) {
+		return "", fmt.Errorf("cannot determine the import path of %s outside of a module or GOPATH", dir)
+	}
+	return pkg.ImportPath, nil
+}
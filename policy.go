@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// violation is an import that breaks one of the dependency rules.
+type violation struct {
+	From, To string
+	Rule     string
+}
+
+// forbidden holds the imports breaking one of the rules, which are
+// highlighted in the dot format.
+var forbidden map[[2]string]bool
+
+// rule is a pair of patterns given on the command line as from=to.
+type rule struct {
+	From, To string
+}
+
+func (r rule) String() string {
+	return r.From + "=" + r.To
+}
+
+// rules is a repeatable command line flag of rules.
+type rules []rule
+
+func (r *rules) String() string {
+	var s []string
+	for _, rule := range *r {
+		s = append(s, rule.String())
+	}
+	return strings.Join(s, " ")
+}
+
+func (r *rules) Set(value string) error {
+	kv := strings.SplitN(value, "=", 2)
+	if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+		return fmt.Errorf("expected from=to, got %q", value)
+	}
+	*r = append(*r, rule{From: kv[0], To: kv[1]})
+	return nil
+}
+
+// matchPattern reports whether an import path matches a rule pattern. A
+// pattern matches the packages whose path starts with it, as well as those
+// that contain it as a sequence of path elements, so that "store" matches
+// both "store/sql" and "github.com/foo/app/store". A trailing "/..." is
+// allowed, as in the go command, and matches the same packages.
+func matchPattern(path, pattern string) bool {
+	pattern = strings.TrimSuffix(pattern, "/...")
+	if path == pattern || strings.HasPrefix(path, pattern+"/") {
+		return true
+	}
+	return strings.HasSuffix(path, "/"+pattern) || strings.Contains(path, "/"+pattern+"/")
+}
+
+// readLayers reads a layers file. Each line declares a chain of layers from
+// the top down, separated by "->", where every layer is a pattern matching
+// the packages it contains. Blank lines and lines starting with # are
+// ignored.
+func readLayers(name string) ([][]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var layers [][]string
+	s := bufio.NewScanner(f)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var chain []string
+		for _, l := range strings.Split(text, "->") {
+			l = strings.TrimSpace(l)
+			if l == "" {
+				return nil, fmt.Errorf("%s:%d: empty layer", name, line)
+			}
+			chain = append(chain, l)
+		}
+		if len(chain) < 2 {
+			return nil, fmt.Errorf("%s:%d: expected at least two layers separated by ->", name, line)
+		}
+		layers = append(layers, chain)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return layers, nil
+}
+
+// layerOf returns the index of the layer in chain that the package belongs
+// to, choosing the longest matching pattern, or -1 if it is in none of them.
+func layerOf(chain []string, path string) int {
+	layer := -1
+	for i, l := range chain {
+		if matchPattern(path, l) && (layer < 0 || len(l) > len(chain[layer])) {
+			layer = i
+		}
+	}
+	return layer
+}
+
+// layerViolations returns the imports from a package to one in a layer above
+// its own.
+func (g *graph) layerViolations(layers [][]string) []violation {
+	var violations []violation
+	for _, e := range g.edges() {
+		for _, chain := range layers {
+			from, to := layerOf(chain, e.From), layerOf(chain, e.To)
+			if from >= 0 && to >= 0 && to < from {
+				violations = append(violations, violation{
+					From: e.From,
+					To:   e.To,
+					Rule: fmt.Sprintf("layer %s may not import layer %s", chain[from], chain[to]),
+				})
+			}
+		}
+	}
+	return violations
+}
+
+// deniedImports returns the imports matching one of the denied rules.
+func (g *graph) deniedImports(denied rules) []violation {
+	var violations []violation
+	for _, e := range g.edges() {
+		for _, r := range denied {
+			if matchPattern(e.From, r.From) && matchPattern(e.To, r.To) {
+				violations = append(violations, violation{
+					From: e.From,
+					To:   e.To,
+					Rule: "denied by " + r.String(),
+				})
+			}
+		}
+	}
+	return violations
+}
+
+// disallowedImports returns the imports of the packages matching one of the
+// allowed rules that match neither the package's own pattern nor any of the
+// patterns it is allowed to import. A package matching several rules may
+// import what any of them allows.
+func (g *graph) disallowedImports(allowed rules) []violation {
+	var violations []violation
+	for _, e := range g.edges() {
+		var matched []string
+		ok := false
+		for _, r := range allowed {
+			if !matchPattern(e.From, r.From) {
+				continue
+			}
+			matched = append(matched, r.From)
+			if matchPattern(e.To, r.From) {
+				ok = true
+			}
+			for _, to := range strings.Split(r.To, ",") {
+				if matchPattern(e.To, to) {
+					ok = true
+				}
+			}
+		}
+		if len(matched) > 0 && !ok {
+			violations = append(violations, violation{
+				From: e.From,
+				To:   e.To,
+				Rule: "not allowed for " + strings.Join(matched, ", "),
+			})
+		}
+	}
+	return violations
+}
+
+// internalParent returns the path of the package tree that may import an
+// internal package, following the rules of the go command. It returns false
+// if the package isn't internal, and an empty path for the internal packages
+// of the standard library.
+func internalParent(path string) (string, bool) {
+	switch {
+	case strings.HasSuffix(path, "/internal"):
+		return strings.TrimSuffix(path, "/internal"), true
+	case strings.Contains(path, "/internal/"):
+		return path[:strings.LastIndex(path, "/internal/")], true
+	case path == "internal", strings.HasPrefix(path, "internal/"):
+		return "", true
+	}
+	return "", false
+}
+
+// internalViolations returns the imports of internal packages that the go
+// command doesn't allow, as well as those it allows across module
+// boundaries, such as from a module to the internal packages of a module
+// nested in it.
+func (g *graph) internalViolations() []violation {
+	var violations []violation
+	for _, e := range g.edges() {
+		from, to := g.node(e.From), g.node(e.To)
+		parent, ok := internalParent(e.To)
+		if !ok || from.Goroot {
+			continue
+		}
+		var rule string
+		switch {
+		case parent == "":
+			rule = "use of an internal package of the standard library"
+		case parent != "" && e.From != parent && !strings.HasPrefix(e.From, parent+"/"):
+			rule = "use of an internal package outside of " + parent
+		case from.Module != "" && to.Module != "" && from.Module != to.Module:
+			rule = "use of an internal package of module " + to.Module
+		default:
+			continue
+		}
+		violations = append(violations, violation{From: e.From, To: e.To, Rule: rule})
+	}
+	return violations
+}
+
+// checkPolicy returns the imports in the graph that break the rules given
+// on the command line.
+func checkPolicy(g *graph) ([]violation, error) {
+	var violations []violation
+	if *layersFile != "" {
+		layers, err := readLayers(*layersFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read layers: %s", err)
+		}
+		violations = append(violations, g.layerViolations(layers)...)
+	}
+	if *baselineFile != "" {
+		known, err := readBaseline(*baselineFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read baseline: %s", err)
+		}
+		violations = append(violations, g.newImports(known)...)
+	}
+	violations = append(violations, g.deniedImports(denyRules)...)
+	violations = append(violations, g.disallowedImports(allowRules)...)
+	if *checkInternal {
+		violations = append(violations, g.internalViolations()...)
+	}
+	forbidden = make(map[[2]string]bool)
+	for _, v := range violations {
+		forbidden[[2]string{v.From, v.To}] = true
+	}
+	return violations, nil
+}
+
+// reportViolations writes a summary of the imports breaking the rules.
+func reportViolations(w io.Writer, violations []violation) {
+	if len(violations) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "found %d forbidden import(s):\n", len(violations))
+	for _, v := range violations {
+		fmt.Fprintf(w, "  %s -> %s: %s\n", processName(v.From), processName(v.To), v.Rule)
+	}
+}
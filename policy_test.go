@@ -0,0 +1,84 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// testGraph returns a graph of the given imports, rooted at roots.
+func testGraph(roots []string, imports map[string][]string) *graph {
+	paths := make(map[string]bool)
+	for from, tos := range imports {
+		paths[from] = true
+		for _, to := range tos {
+			paths[to] = true
+		}
+	}
+	g := &graph{Roots: roots}
+	for p := range paths {
+		g.Nodes = append(g.Nodes, &node{ImportPath: p, Imports: imports[p]})
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		return g.Nodes[i].ImportPath < g.Nodes[j].ImportPath
+	})
+	return g
+}
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		path, pattern string
+		want          bool
+	}{
+		{"store", "store", true},
+		{"store/sql", "store", true},
+		{"github.com/foo/app/store", "store", true},
+		{"github.com/foo/app/store/sql", "store", true},
+		{"storefront", "store", false},
+		{"github.com/foo/app/restore", "store", false},
+		{"github.com/foo/bar/internal", "github.com/foo/bar/internal/...", true},
+		{"github.com/foo/bar/internal/x", "github.com/foo/bar/internal/...", true},
+		{"github.com/foo/bar/internals", "github.com/foo/bar/internal/...", false},
+		{"github.com/foo/bar/cmd/baz", "cmd/...", true},
+	}
+	for _, tt := range tests {
+		if got := matchPattern(tt.path, tt.pattern); got != tt.want {
+			t.Errorf("matchPattern(%q, %q) = %v, want %v", tt.path, tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestDeniedImports(t *testing.T) {
+	g := testGraph([]string{"github.com/foo/bar/internal/x"}, map[string][]string{
+		"github.com/foo/bar/internal/x": {"github.com/foo/bar/cmd/y", "github.com/foo/bar/lib"},
+		"github.com/foo/bar/lib":        {"github.com/foo/bar/cmd/y"},
+	})
+	// The rule of the configuration file example of the README.
+	var denied rules
+	if err := denied.Set("github.com/foo/bar/internal/...=github.com/foo/bar/cmd/..."); err != nil {
+		t.Fatal(err)
+	}
+	want := []violation{{
+		From: "github.com/foo/bar/internal/x",
+		To:   "github.com/foo/bar/cmd/y",
+		Rule: "denied by github.com/foo/bar/internal/...=github.com/foo/bar/cmd/...",
+	}}
+	if got := g.deniedImports(denied); !reflect.DeepEqual(got, want) {
+		t.Errorf("deniedImports() = %v, want %v", got, want)
+	}
+}
+
+func TestDisallowedImports(t *testing.T) {
+	g := testGraph([]string{"app/domain"}, map[string][]string{
+		"app/domain":      {"app/domain/user", "errors", "net/http"},
+		"app/domain/user": {"errors"},
+	})
+	var allowed rules
+	if err := allowed.Set("app/domain=errors,fmt"); err != nil {
+		t.Fatal(err)
+	}
+	want := []violation{{From: "app/domain", To: "net/http", Rule: "not allowed for app/domain"}}
+	if got := g.disallowedImports(allowed); !reflect.DeepEqual(got, want) {
+		t.Errorf("disallowedImports() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+// startProfiles starts writing a CPU profile to cpuFile, if not empty, and
+// returns a function that stops it and writes a heap profile to memFile, if
+// not empty, to be called before exiting.
+func startProfiles(cpuFile, memFile string) (func(), error) {
+	var cpu *os.File
+	if cpuFile != "" {
+		var err error
+		if cpu, err = os.Create(cpuFile); err != nil {
+			return nil, fmt.Errorf("failed to create CPU profile: %s", err)
+		}
+		if err := pprof.StartCPUProfile(cpu); err != nil {
+			cpu.Close()
+			return nil, fmt.Errorf("failed to start CPU profile: %s", err)
+		}
+	}
+	return func() {
+		if cpu != nil {
+			pprof.StopCPUProfile()
+			cpu.Close()
+		}
+		if memFile != "" {
+			if err := writeHeapProfile(memFile); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to write memory profile: %s\n", err)
+			}
+		}
+	}, nil
+}
+
+func writeHeapProfile(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	// Get up-to-date statistics, as done by go test -memprofile.
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
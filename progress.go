@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// progressInterval is the minimum time between two progress reports.
+const progressInterval = time.Second
+
+// progress reports how a scan is going when -v is given, since scanning a
+// large repository can take a while without any other output.
+type progress struct {
+	w     io.Writer
+	start time.Time
+	last  time.Time
+}
+
+// newProgress returns a progress reporter writing to w, or nil if neither -v
+// nor -vv is given. A nil reporter reports nothing.
+func newProgress(w io.Writer) *progress {
+	if verbosity < levelVerbose {
+		return nil
+	}
+	now := time.Now()
+	return &progress{w: w, start: now, last: now}
+}
+
+// update reports the number of packages scanned and still queued, at most
+// once per progressInterval.
+func (p *progress) update(scanned, queued int) {
+	if p == nil {
+		return
+	}
+	now := time.Now()
+	if now.Sub(p.last) < progressInterval {
+		return
+	}
+	p.last = now
+	fmt.Fprintf(p.w, "scanned %d package(s), %d queued, %s elapsed\n", scanned, queued, p.elapsed())
+}
+
+// done reports the number of packages scanned in total.
+func (p *progress) done(scanned int) {
+	if p == nil {
+		return
+	}
+	fmt.Fprintf(p.w, "scanned %d package(s) in %s\n", scanned, p.elapsed())
+}
+
+func (p *progress) elapsed() time.Duration {
+	return time.Since(p.start).Round(time.Millisecond)
+}
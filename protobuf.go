@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+)
+
+// Protocol buffer encoding of the graph, following the schema in graph.proto.
+// The messages are small enough to be encoded by hand, which avoids depending
+// on the protobuf runtime.
+
+// protoEdgeTypes maps edge types to the values of the EdgeType enum.
+var protoEdgeTypes = map[string]int{
+	edgeImport: 1,
+	edgeTest:   2,
+	edgeXTest:  3,
+}
+
+// protoEdgeTypeNames holds the names of the EdgeType enum values, as used by
+// the JSON mapping.
+var protoEdgeTypeNames = []string{
+	"EDGE_TYPE_UNSPECIFIED",
+	"EDGE_TYPE_IMPORT",
+	"EDGE_TYPE_TEST",
+	"EDGE_TYPE_XTEST",
+}
+
+// protoBuffer accumulates an encoded message. Fields with the default value
+// are skipped, as in proto3.
+type protoBuffer []byte
+
+func (b *protoBuffer) varint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	*b = append(*b, buf[:n]...)
+}
+
+func (b *protoBuffer) tag(field, wireType int) {
+	b.varint(uint64(field<<3 | wireType))
+}
+
+func (b *protoBuffer) bytesField(field int, v []byte) {
+	b.tag(field, 2)
+	b.varint(uint64(len(v)))
+	*b = append(*b, v...)
+}
+
+func (b *protoBuffer) stringField(field int, v string) {
+	if v != "" {
+		b.bytesField(field, []byte(v))
+	}
+}
+
+func (b *protoBuffer) uintField(field int, v uint64) {
+	if v != 0 {
+		b.tag(field, 0)
+		b.varint(v)
+	}
+}
+
+func (b *protoBuffer) boolField(field int, v bool) {
+	if v {
+		b.uintField(field, 1)
+	}
+}
+
+// writeProto writes the graph as a binary Graph message.
+func writeProto(w io.Writer, g *graph) error {
+	var msg protoBuffer
+	for _, n := range g.Nodes {
+		var nm protoBuffer
+		nm.stringField(1, n.ImportPath)
+		nm.stringField(2, n.Dir)
+		nm.boolField(3, n.Goroot)
+		nm.boolField(4, n.Cgo)
+		nm.boolField(5, n.Vendored)
+		nm.stringField(6, n.Module)
+		nm.stringField(7, n.Version)
+		msg.bytesField(1, nm)
+	}
+	for _, e := range g.edges() {
+		var em protoBuffer
+		em.stringField(1, e.From)
+		em.stringField(2, e.To)
+		em.uintField(3, uint64(protoEdgeTypes[e.Type]))
+		em.uintField(4, uint64(e.Weight))
+		msg.bytesField(2, em)
+	}
+	for _, root := range g.Roots {
+		msg.bytesField(3, []byte(root))
+	}
+	_, err := w.Write(msg)
+	return err
+}
+
+type protoJSONGraph struct {
+	Nodes []protoJSONNode `json:"nodes,omitempty"`
+	Edges []protoJSONEdge `json:"edges,omitempty"`
+	Roots []string        `json:"roots,omitempty"`
+}
+
+type protoJSONNode struct {
+	ImportPath string `json:"importPath,omitempty"`
+	Dir        string `json:"dir,omitempty"`
+	Goroot     bool   `json:"goroot,omitempty"`
+	Cgo        bool   `json:"cgo,omitempty"`
+	Vendored   bool   `json:"vendored,omitempty"`
+	Module     string `json:"module,omitempty"`
+	Version    string `json:"version,omitempty"`
+}
+
+type protoJSONEdge struct {
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+	Type   string `json:"type,omitempty"`
+	Weight int    `json:"weight,omitempty"`
+}
+
+// writeProtoJSON writes the graph as a Graph message in the protobuf JSON
+// mapping.
+func writeProtoJSON(w io.Writer, g *graph) error {
+	var pg protoJSONGraph
+	for _, n := range g.Nodes {
+		pg.Nodes = append(pg.Nodes, protoJSONNode{
+			ImportPath: n.ImportPath,
+			Dir:        n.Dir,
+			Goroot:     n.Goroot,
+			Cgo:        n.Cgo,
+			Vendored:   n.Vendored,
+			Module:     n.Module,
+			Version:    n.Version,
+		})
+	}
+	for _, e := range g.edges() {
+		pg.Edges = append(pg.Edges, protoJSONEdge{
+			From:   e.From,
+			To:     e.To,
+			Type:   protoEdgeTypeNames[protoEdgeTypes[e.Type]],
+			Weight: e.Weight,
+		})
+	}
+	pg.Roots = g.Roots
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(pg)
+}
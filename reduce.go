@@ -0,0 +1,71 @@
+package main
+
+// reduce returns the transitive reduction of the graph: the imports of a
+// package that it also depends on indirectly through its other imports are
+// removed, which keeps the same packages reachable from each other. The
+// imports within an import cycle are kept, as are the imports between two
+// cycles unless the second one is reachable from the first through another
+// package.
+func (g *graph) reduce() *graph {
+	comp := make(map[string]string)
+	for _, n := range g.Nodes {
+		comp[n.ImportPath] = n.ImportPath
+	}
+	for _, c := range g.sccs() {
+		for _, p := range c {
+			comp[p] = c[0]
+		}
+	}
+
+	// succs holds the imports between components, in the order they are
+	// first seen.
+	succs := make(map[string][]string)
+	linked := make(map[[2]string]bool)
+	for _, n := range g.Nodes {
+		from := comp[n.ImportPath]
+		for _, imp := range n.Imports {
+			to := comp[imp]
+			if from != to && !linked[[2]string{from, to}] {
+				linked[[2]string{from, to}] = true
+				succs[from] = append(succs[from], to)
+			}
+		}
+	}
+
+	// redundant holds the imports between components that are implied by a
+	// longer chain of imports.
+	redundant := make(map[[2]string]bool)
+	for from, direct := range succs {
+		seen := make(map[string]bool)
+		var queue []string
+		for _, s := range direct {
+			for _, t := range succs[s] {
+				if !seen[t] {
+					seen[t] = true
+					queue = append(queue, t)
+				}
+			}
+		}
+		for len(queue) > 0 {
+			p := queue[0]
+			queue = queue[1:]
+			for _, t := range succs[p] {
+				if !seen[t] {
+					seen[t] = true
+					queue = append(queue, t)
+				}
+			}
+		}
+		for _, s := range direct {
+			if seen[s] {
+				redundant[[2]string{from, s}] = true
+			}
+		}
+	}
+
+	return g.subgraph(func(n *node) bool {
+		return true
+	}, func(from, to string) bool {
+		return !redundant[[2]string{comp[from], comp[to]}]
+	})
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReduce(t *testing.T) {
+	tests := []struct {
+		name          string
+		imports, want map[string][]string
+	}{
+		{
+			name: "chain",
+			imports: map[string][]string{
+				"a": {"b", "c", "d"},
+				"b": {"c"},
+				"c": {"d"},
+			},
+			want: map[string][]string{
+				"a": {"b"},
+				"b": {"c"},
+				"c": {"d"},
+			},
+		},
+		{
+			name: "diamond",
+			imports: map[string][]string{
+				"a": {"b", "c"},
+				"b": {"d"},
+				"c": {"d"},
+			},
+			want: map[string][]string{
+				"a": {"b", "c"},
+				"b": {"d"},
+				"c": {"d"},
+			},
+		},
+		{
+			name: "cycle",
+			imports: map[string][]string{
+				"a": {"b", "d"},
+				"b": {"c"},
+				"c": {"b", "d"},
+			},
+			want: map[string][]string{
+				"a": {"b"},
+				"b": {"c"},
+				"c": {"b", "d"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		g := testGraph([]string{"a"}, tt.imports).reduce()
+		if got := graphImports(g); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: reduce() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// graphImports returns the imports of the packages of g that import any.
+func graphImports(g *graph) map[string][]string {
+	imports := make(map[string][]string)
+	for _, n := range g.Nodes {
+		if len(n.Imports) > 0 {
+			imports[n.ImportPath] = n.Imports
+		}
+	}
+	return imports
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+// serveFilters are the filters that can be changed from the page served by
+// the serve command, overriding -focus, -hops, -exclude-re and -s.
+type serveFilters struct {
+	Focus        string
+	Hops         int
+	Exclude      string
+	IgnoreStdlib bool
+}
+
+// parseFilters returns the filters given in the query of a request, or the
+// defaults if the form wasn't submitted.
+func parseFilters(q url.Values, defaults serveFilters) (serveFilters, error) {
+	if len(q) == 0 {
+		return defaults, nil
+	}
+	f := serveFilters{
+		Focus:        q.Get("focus"),
+		Hops:         1,
+		Exclude:      q.Get("exclude"),
+		IgnoreStdlib: q.Get("s") != "",
+	}
+	if h := q.Get("hops"); h != "" {
+		hops, err := strconv.Atoi(h)
+		if err != nil || hops < 1 {
+			return f, fmt.Errorf("wrong hops: %s", h)
+		}
+		f.Hops = hops
+	}
+	return f, nil
+}
+
+// serve serves the graph of the named packages as the interactive HTML page
+// on addr, scanning the packages again for each request so that the page is
+// up to date when reloaded.
+func serve(addr, cwd string, args []string) error {
+	defaults := serveFilters{
+		Focus:        *focus,
+		Hops:         *focusHops,
+		Exclude:      *excludePattern,
+		IgnoreStdlib: *ignoreStdlib,
+	}
+	// The filters are global flags, so requests are handled one at a time.
+	var mu sync.Mutex
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		f, err := parseFilters(r.URL.Query(), defaults)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		exclude, err := regexp.Compile(f.Exclude)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("wrong exclude pattern: %s", err), http.StatusBadRequest)
+			return
+		}
+		if f.Exclude == "" {
+			exclude = nil
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		*focus, *focusHops, *ignoreStdlib, excludeRegexp = f.Focus, f.Hops, f.IgnoreStdlib, exclude
+		var buf bytes.Buffer
+		_, _, err = run(cwd, args, nopCloser{&buf}, func(w io.Writer, g *graph) error {
+			return writeHTMLPage(w, g, &f)
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		buf.WriteTo(w)
+	})
+	log.Printf("serving the graph on http://%s", addr)
+	return http.ListenAndServe(addr, nil)
+}
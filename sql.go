@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// writeSQL writes the graph as an SQL script creating and filling a packages
+// and an imports table.
+func writeSQL(w io.Writer, g *graph) error {
+	fmt.Fprint(w, `BEGIN TRANSACTION;
+DROP TABLE IF EXISTS imports;
+DROP TABLE IF EXISTS packages;
+CREATE TABLE packages (
+  import_path TEXT PRIMARY KEY,
+  dir TEXT NOT NULL,
+  goroot BOOLEAN NOT NULL,
+  cgo BOOLEAN NOT NULL,
+  vendored BOOLEAN NOT NULL,
+  module TEXT,
+  version TEXT
+);
+CREATE TABLE imports (
+  from_path TEXT NOT NULL REFERENCES packages (import_path),
+  to_path TEXT NOT NULL REFERENCES packages (import_path),
+  type TEXT NOT NULL,
+  weight INTEGER NOT NULL,
+  PRIMARY KEY (from_path, to_path)
+);
+`)
+	for _, n := range g.Nodes {
+		fmt.Fprintf(w, "INSERT INTO packages VALUES (%s, %s, %d, %d, %d, %s, %s);\n",
+			sqlString(n.ImportPath), sqlString(n.Dir), sqlBool(n.Goroot), sqlBool(n.Cgo), sqlBool(n.Vendored), sqlNullString(n.Module), sqlNullString(n.Version))
+	}
+	for _, e := range g.edges() {
+		fmt.Fprintf(w, "INSERT INTO imports VALUES (%s, %s, %s, %d);\n",
+			sqlString(e.From), sqlString(e.To), sqlString(e.Type), e.Weight)
+	}
+	_, err := fmt.Fprintln(w, "COMMIT;")
+	return err
+}
+
+// writeSQLite writes the graph into the SQLite database named by -output using
+// the sqlite3 command line tool.
+func writeSQLite(sqlite3, db string, g *graph) error {
+	var buf bytes.Buffer
+	if err := writeSQL(&buf, g); err != nil {
+		return err
+	}
+	cmd := exec.Command(sqlite3, "-bail", db)
+	cmd.Stdin = &buf
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("sqlite3 failed: %s", err)
+	}
+	return nil
+}
+
+func sqlString(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+func sqlNullString(s string) string {
+	if s == "" {
+		return "NULL"
+	}
+	return sqlString(s)
+}
+
+func sqlBool(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
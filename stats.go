@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+// packageStats holds the coupling metrics of a package, including Robert
+// Martin's instability and abstractness.
+type packageStats struct {
+	ImportPath string `json:"importPath"`
+	// Afferent is the number of packages importing the package (Ca).
+	Afferent int `json:"afferent"`
+	// Efferent is the number of packages the package imports (Ce).
+	Efferent int `json:"efferent"`
+	// Instability is Ce/(Ca+Ce).
+	Instability float64 `json:"instability"`
+	// Abstractness is the ratio of exported interfaces to exported types.
+	Abstractness float64 `json:"abstractness"`
+	// Distance is the distance from the main sequence, |A+I-1|.
+	Distance float64 `json:"distance"`
+	// Transitive is the number of packages the package depends on, directly
+	// or indirectly.
+	Transitive int `json:"transitive"`
+	// Betweenness is the number of shortest import chains between other
+	// packages that go through the package.
+	Betweenness float64 `json:"betweenness"`
+}
+
+// transitiveCounts holds the number of transitive dependencies of every
+// package when they are shown in the labels.
+var transitiveCounts map[string]int
+
+// statsSorts are the orders the stats report can be sorted in.
+var statsSorts = map[string]func(a, b packageStats) bool{
+	"name": func(a, b packageStats) bool { return a.ImportPath < b.ImportPath },
+	"ca":   func(a, b packageStats) bool { return a.Afferent > b.Afferent },
+	"ce":   func(a, b packageStats) bool { return a.Efferent > b.Efferent },
+	"i":    func(a, b packageStats) bool { return a.Instability > b.Instability },
+	"a":    func(a, b packageStats) bool { return a.Abstractness > b.Abstractness },
+	"d":    func(a, b packageStats) bool { return a.Distance > b.Distance },
+	"deps": func(a, b packageStats) bool { return a.Transitive > b.Transitive },
+	"b":    func(a, b packageStats) bool { return a.Betweenness > b.Betweenness },
+}
+
+// stats computes the coupling metrics of every package of the graph.
+func (g *graph) stats() []packageStats {
+	importers := g.importers()
+	betweenness := g.betweenness()
+	var stats []packageStats
+	for _, n := range g.Nodes {
+		s := packageStats{
+			ImportPath:   n.ImportPath,
+			Afferent:     len(importers[n.ImportPath]),
+			Efferent:     len(n.Imports),
+			Abstractness: abstractness(n),
+			Transitive:   g.transitiveCount(n.ImportPath),
+			Betweenness:  betweenness[n.ImportPath],
+		}
+		s.Instability = instability(s.Afferent, s.Efferent)
+		s.Distance = mainSequenceDistance(s.Abstractness, s.Instability)
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+// godPackages holds the packages that import and are imported by too many
+// packages, when -god-ca and -god-ce are given.
+var godPackages map[string]bool
+
+// gods returns the packages that are imported by more than ca packages and
+// import more than ce packages, which usually do too much. Only their
+// afferent and efferent coupling are filled in.
+func (g *graph) gods(ca, ce int) []packageStats {
+	importers := g.importers()
+	var gods []packageStats
+	for _, n := range g.Nodes {
+		s := packageStats{
+			ImportPath: n.ImportPath,
+			Afferent:   len(importers[n.ImportPath]),
+			Efferent:   len(n.Imports),
+		}
+		if s.Afferent > ca && s.Efferent > ce {
+			gods = append(gods, s)
+		}
+	}
+	return gods
+}
+
+// reportGods writes a summary of the god packages.
+func reportGods(w io.Writer, gods []packageStats) {
+	if len(gods) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "found %d god package(s):\n", len(gods))
+	for _, s := range gods {
+		fmt.Fprintf(w, "  %s: imported by %d, imports %d\n", processName(s.ImportPath), s.Afferent, s.Efferent)
+	}
+}
+
+// writeStats writes the coupling metrics of the packages, sorted by the given
+// order, as a table or as JSON.
+func writeStats(w io.Writer, g *graph, order string, asJSON bool) error {
+	stats := g.stats()
+	less := statsSorts[order]
+	sort.SliceStable(stats, func(i, j int) bool {
+		return less(stats[i], stats[j])
+	})
+
+	if asJSON {
+		if stats == nil {
+			stats = []packageStats{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(stats)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "PACKAGE\tCA\tCE\tI\tA\tD\tDEPS\tB")
+	for _, s := range stats {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.2f\t%.2f\t%.2f\t%d\t%.1f\n", processName(s.ImportPath), s.Afferent, s.Efferent, s.Instability, s.Abstractness, s.Distance, s.Transitive, s.Betweenness)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// subcommand is a command of godepgraph taking only the flags that apply to
+// it, as an alternative to the flat command line where every flag is
+// accepted everywhere.
+type subcommand struct {
+	name    string
+	args    string
+	summary string
+	// command is the command of the flat command line that the subcommand
+	// runs, if any.
+	command string
+	// flags lists the groups of flags that the subcommand takes.
+	flags [][]string
+}
+
+// Groups of flags taken by the subcommands.
+var (
+	scanFlags = []string{
+		"s", "d", "p", "i", "o", "include-re", "exclude-re", "x", "tags", "t", "l",
+		"platform", "mod", "golist", "cache", "incremental", "k", "nested", "workspace",
+		"max-nodes", "max-edges", "config", "v", "vv", "q", "stats", "cpuprofile", "memprofile",
+	}
+	outputFlags = []string{
+		"format", "output", "T", "render", "watch", "dry-run", "horizontal", "c", "r",
+		"cluster-modules", "granularity", "collapse", "collapse-std", "reduce", "without",
+		"focus", "hops", "cycles-only", "condense", "count-deps", "color-distance",
+		"color-cgo", "color-unsafe", "color-replaced", "versions", "size-lines", "weights",
+		"duplicates",
+	}
+	pathFlags  = []string{"from", "to", "shortest", "reverse"}
+	checkFlags = []string{
+		"deny", "allow", "layers", "internal", "baseline", "write-baseline",
+		"max-packages", "max-deps", "max-depth", "god-ca", "god-ce", "duplicates",
+	}
+)
+
+var subcommands = []*subcommand{
+	{
+		name:    "graph",
+		args:    "<package>...",
+		summary: "write the dependency graph of the packages",
+		flags:   [][]string{scanFlags, outputFlags},
+	},
+	{
+		name:    "check",
+		args:    "<package>...",
+		summary: "check the dependency rules and budgets, exiting with status 1 if one is violated",
+		command: "check",
+		flags:   [][]string{scanFlags, checkFlags},
+	},
+	{
+		name:    "stats",
+		args:    "<package>...",
+		summary: "write the coupling metrics of the packages",
+		command: "stats",
+		flags:   [][]string{scanFlags, {"sort", "format", "output"}},
+	},
+	{
+		name:    "diff",
+		args:    "<old-ref> <new-ref> <package>... | <old.json> <new.json>",
+		summary: "compare the graphs of two revisions or snapshots",
+		command: "diff",
+		flags:   [][]string{scanFlags, {"format", "output"}},
+	},
+	{
+		name:    "paths",
+		args:    "<package>...",
+		summary: "write the import paths from the packages, or from -from, to the package given to -to",
+		flags:   [][]string{scanFlags, outputFlags, pathFlags},
+	},
+	{
+		name:    "serve",
+		args:    "<package>...",
+		summary: "serve the interactive HTML viewer of the graph",
+		command: "serve",
+		flags:   [][]string{scanFlags, {"addr", "focus", "hops", "horizontal", "c", "r"}},
+	},
+}
+
+// flatCommands are the commands only available on the flat command line.
+var flatCommands = []string{
+	"why", "common", "orphans", "chain", "cgo", "unsafe", "top", "testonly",
+	"modgraph", "explore", "dominators", "impact",
+}
+
+// subcommandOf returns the subcommand given as the first argument, or nil
+// if the flat command line is used.
+func subcommandOf(args []string) *subcommand {
+	if len(args) == 0 {
+		return nil
+	}
+	for _, sc := range subcommands {
+		if sc.name == args[0] {
+			return sc
+		}
+	}
+	return nil
+}
+
+// flagSet returns the flags of the subcommand, which only check its command
+// line: the flags are set when it is parsed again as a flat one.
+func (sc *subcommand) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(sc.name, flag.ExitOnError)
+	for _, group := range sc.flags {
+		for _, name := range group {
+			if fs.Lookup(name) != nil {
+				continue
+			}
+			f := flag.Lookup(name)
+			var v interface{}
+			if g, ok := f.Value.(flag.Getter); ok {
+				v = g.Get()
+			}
+			switch v := v.(type) {
+			case bool:
+				fs.Bool(f.Name, v, f.Usage)
+			case int:
+				fs.Int(f.Name, v, f.Usage)
+			default:
+				fs.String(f.Name, f.DefValue, f.Usage)
+			}
+		}
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: godepgraph %s [flags] %s\n\n%s.\n\nflags:\n", sc.name, sc.args, sc.summary)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// flatArgs checks the command line of the subcommand, and returns the flat
+// command line that runs it.
+func (sc *subcommand) flatArgs(args []string) []string {
+	fs := sc.flagSet()
+	fs.Parse(args)
+	rest := fs.Args()
+	flags := args[:len(args)-len(rest)]
+	if sc.name == "paths" && !flagGiven(fs, "to") {
+		fmt.Fprintln(os.Stderr, "paths requires -to")
+		fs.Usage()
+		os.Exit(exitError)
+	}
+	if sc.command != "" {
+		rest = append([]string{sc.command}, rest...)
+	}
+	return append(append([]string(nil), flags...), rest...)
+}
+
+// flagGiven reports whether the named flag was given to fs.
+func flagGiven(fs *flag.FlagSet, name string) bool {
+	given := false
+	fs.Visit(func(f *flag.Flag) {
+		given = given || f.Name == name
+	})
+	return given
+}
+
+// usage writes the usage of godepgraph, listing the subcommands before the
+// flags of the flat command line.
+func usage() {
+	w := flag.CommandLine.Output()
+	fmt.Fprintln(w, "usage: godepgraph <command> [flags] <package>...")
+	fmt.Fprintln(w, "       godepgraph [flags] [command] <package>...")
+	fmt.Fprintln(w, "\ncommands, see godepgraph <command> -h for their flags:")
+	for _, sc := range subcommands {
+		fmt.Fprintf(w, "  %-6s  %s\n", sc.name, sc.summary)
+	}
+	fmt.Fprintln(w, "\nflags:")
+	flag.PrintDefaults()
+	fmt.Fprintln(w, "\nThe flat command line takes every flag, and the other commands: "+strings.Join(flatCommands, ", ")+".")
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// summary holds how long each step of a run took and how many packages were
+// scanned, for -stats.
+type summary struct {
+	// Scanned is the number of packages imported, Ignored the number of them
+	// ignored by -s, -i, -p or -o, and TooDeep the number of imports not
+	// followed because of -l.
+	Scanned, Ignored, TooDeep int
+	// Filtered is the number of packages removed from the graph after the
+	// scan, such as by -to, -focus or -without.
+	Filtered int
+	Nodes    int
+	Edges    int
+	// Skipped counts the packages left out of the graph during the scan, and
+	// the imports of them, by the reason they were skipped.
+	Skipped map[string]*skipCount
+
+	Load, Analysis, Output time.Duration
+}
+
+// skipCount is a number of packages left out of the graph, and of imports
+// of them.
+type skipCount struct {
+	Packages, Imports int
+}
+
+// runSummary is the summary of the current run.
+var runSummary summary
+
+// skip counts a package skipped for the given reason, with the number of
+// imports of it.
+func (s *summary) skip(reason string, imports int) {
+	if s.Skipped == nil {
+		s.Skipped = make(map[string]*skipCount)
+	}
+	c := s.Skipped[reason]
+	if c == nil {
+		c = &skipCount{}
+		s.Skipped[reason] = c
+	}
+	c.Packages++
+	c.Imports += imports
+}
+
+// reportSummary writes the summary of the run.
+func reportSummary(w io.Writer, s summary) {
+	fmt.Fprintf(w, "loaded %d package(s) in %s: %d ignored, %d beyond -l\n", s.Scanned, s.Load.Round(time.Millisecond), s.Ignored, s.TooDeep)
+	fmt.Fprintf(w, "analyzed the graph in %s: %d package(s) filtered out\n", s.Analysis.Round(time.Millisecond), s.Filtered)
+	fmt.Fprintf(w, "wrote %d package(s) and %d import(s) in %s\n", s.Nodes, s.Edges, s.Output.Round(time.Millisecond))
+}
+
+// writeDryRun writes the number of packages and imports of the graph, and of
+// those left out of it by each filter, instead of the graph itself, given the
+// graph as scanned and after the transformations.
+func writeDryRun(w io.Writer, scanned, g *graph) error {
+	var reasons []string
+	for reason := range runSummary.Skipped {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "\tPACKAGES\tIMPORTS")
+	fmt.Fprintf(tw, "kept\t%d\t%d\n", len(g.Nodes), len(g.edges()))
+	for _, reason := range reasons {
+		c := runSummary.Skipped[reason]
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", reason, c.Packages, c.Imports)
+	}
+	if filtered := len(scanned.Nodes) - len(g.Nodes); filtered > 0 {
+		fmt.Fprintf(tw, "filtered out after the scan\t%d\t%d\n", filtered, len(scanned.edges())-len(g.edges()))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// cgoTainted holds the packages that depend on cgo without using it
+// themselves, when they are colored with -color-cgo.
+var cgoTainted map[string]bool
+
+// unsafeTainted holds the packages that import unsafe or depend on a package
+// that does, when they are colored with -color-unsafe.
+var unsafeTainted map[string]bool
+
+// chainsTo returns for each package of the graph for which source returns
+// true or that depends on such a package the shortest import chain from it
+// to one of them. The chain of a source package is just that package.
+func (g *graph) chainsTo(source func(n *node) bool) map[string][]string {
+	importers := g.importers()
+	next := make(map[string]string)
+	var queue []string
+	for _, n := range g.Nodes {
+		if source(n) {
+			next[n.ImportPath] = ""
+			queue = append(queue, n.ImportPath)
+		}
+	}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, imp := range importers[p] {
+			if _, ok := next[imp]; !ok {
+				next[imp] = p
+				queue = append(queue, imp)
+			}
+		}
+	}
+
+	chains := make(map[string][]string)
+	for p := range next {
+		chain := []string{p}
+		for q := next[p]; q != ""; q = next[q] {
+			chain = append(chain, q)
+		}
+		chains[p] = chain
+	}
+	return chains
+}
+
+func isCgo(n *node) bool {
+	return n.Cgo
+}
+
+// writeCgo writes the packages that can't be built with CGO_ENABLED=0
+// because they use cgo or depend on a package that does, with the shortest
+// import chain leading to cgo for the latter.
+func writeCgo(w io.Writer, g *graph) error {
+	return writeChainsTo(w, g, g.chainsTo(isCgo), "cgo")
+}
+
+// usesUnsafe reports whether a package outside of the standard library
+// imports unsafe. The standard library is left out since most of the
+// packages depend on one of its packages that does.
+func usesUnsafe(n *node) bool {
+	return n.usesUnsafe && !n.Goroot
+}
+
+// writeUnsafe writes the packages that import unsafe or depend on a package
+// that does, with the shortest import chain leading to unsafe for the
+// latter.
+func writeUnsafe(w io.Writer, g *graph) error {
+	return writeChainsTo(w, g, g.chainsTo(usesUnsafe), "unsafe")
+}
+
+// writeChainsTo writes the packages that have a chain leading to what, with
+// the chain for the packages that don't use it themselves.
+func writeChainsTo(w io.Writer, g *graph, chains map[string][]string, what string) error {
+	if len(chains) == 0 {
+		_, err := fmt.Fprintf(w, "no package depends on %s\n", what)
+		return err
+	}
+	fmt.Fprintf(w, "%d package(s) depend on %s:\n", len(chains), what)
+	for _, n := range g.Nodes {
+		chain, ok := chains[n.ImportPath]
+		if !ok {
+			continue
+		}
+		var line string
+		if len(chain) == 1 {
+			line = processName(n.ImportPath) + " (uses " + what + ")"
+		} else {
+			var names []string
+			for _, p := range chain {
+				names = append(names, processName(p))
+			}
+			line = strings.Join(names, " -> ")
+		}
+		if _, err := fmt.Fprintf(w, "  %s\n", line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
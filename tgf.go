@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// writeTGF writes the graph in Trivial Graph Format: one line per node with
+// its ID and label, a "#" separator, then one line per edge.
+func writeTGF(w io.Writer, g *graph) error {
+	for _, n := range g.Nodes {
+		fmt.Fprintf(w, "%d %s\n", getId(n.ImportPath), processName(n.ImportPath))
+	}
+	fmt.Fprintln(w, "#")
+	for _, e := range g.edges() {
+		if _, err := fmt.Fprintf(w, "%d %d\n", getId(e.From), getId(e.To)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
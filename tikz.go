@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{2})([0-9a-fA-F]{2})([0-9a-fA-F]{2})$`)
+
+// tikzColor returns the name under which a Graphviz color is defined in the
+// picture, and its RGB value as expected by \definecolor. It returns false if
+// the color is neither an X11 color name nor a #rrggbb value.
+func tikzColor(color string) (name, rgb string, ok bool) {
+	if m := hexColor.FindStringSubmatch(color); m != nil {
+		var c []string
+		for _, h := range m[1:] {
+			v, _ := strconv.ParseUint(h, 16, 8)
+			c = append(c, strconv.FormatUint(v, 10))
+		}
+		return "hex" + strings.ToLower(color[1:]), strings.Join(c, ","), true
+	}
+	name = strings.ToLower(strings.Replace(color, " ", "", -1))
+	c, ok := x11Colors[name]
+	if !ok {
+		return "", "", false
+	}
+	return name, fmt.Sprintf("%d,%d,%d", c[0], c[1], c[2]), true
+}
+
+// writeTikZ writes the graph as a TikZ picture with the packages laid out in
+// layers by their distance from the top-level packages. The picture needs the
+// arrows.meta TikZ library. The colors of the packages are defined in the
+// picture, as most of them aren't known to xcolor; packages with any other
+// color are left unfilled.
+func writeTikZ(w io.Writer, g *graph) error {
+	fmt.Fprintln(w, `% \usepackage{tikz} \usetikzlibrary{arrows.meta}`)
+	colors := make(map[string]string)
+	for _, n := range g.Nodes {
+		if name, rgb, ok := tikzColor(nodeColor(n)); ok {
+			colors[name] = rgb
+		}
+	}
+	var names []string
+	for name := range colors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "\\definecolor{%s}{RGB}{%s}\n", name, colors[name])
+	}
+	fmt.Fprintln(w, `\begin{tikzpicture}[>={Stealth}, pkg/.style={draw, rounded corners, font=\ttfamily\small}]`)
+
+	for d, layer := range g.layers() {
+		for i, n := range layer {
+			along := float64(i) - float64(len(layer)-1)/2
+			x, y := along*4, float64(-d)*1.5
+			if *horizontal {
+				x, y = float64(d)*5, 0-along
+			}
+			style := "pkg"
+			if name, _, ok := tikzColor(nodeColor(n)); ok {
+				style += ", fill=" + name
+			}
+			fmt.Fprintf(w, "\\node[%s] (n%d) at (%.2f, %.2f) {%s};\n",
+				style, getId(n.ImportPath), x, y, tikzEscape(processName(n.ImportPath)))
+		}
+	}
+	for _, e := range g.edges() {
+		fmt.Fprintf(w, "\\draw[->] (n%d) -- (n%d);\n", getId(e.From), getId(e.To))
+	}
+
+	_, err := fmt.Fprintln(w, `\end{tikzpicture}`)
+	return err
+}
+
+var tikzReplacer = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`_`, `\_`,
+	`&`, `\&`,
+	`%`, `\%`,
+	`$`, `\$`,
+	`#`, `\#`,
+	`~`, `\textasciitilde{}`,
+	`^`, `\textasciicircum{}`,
+)
+
+// tikzEscape escapes the characters that are special in LaTeX.
+func tikzEscape(s string) string {
+	return tikzReplacer.Replace(s)
+}
@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestTikZColor(t *testing.T) {
+	tests := []struct {
+		color, name, rgb string
+		ok               bool
+	}{
+		{"palegreen", "palegreen", "152,251,152", true},
+		{"lightgoldenrod1", "lightgoldenrod1", "255,236,139", true},
+		{"Misty Rose", "mistyrose", "255,228,225", true},
+		{"#98FB98", "hex98fb98", "152,251,152", true},
+		{"#ff6060", "hexff6060", "255,96,96", true},
+		{"#fff", "", "", false},
+		{"nocolor", "", "", false},
+	}
+	for _, tt := range tests {
+		name, rgb, ok := tikzColor(tt.color)
+		if name != tt.name || rgb != tt.rgb || ok != tt.ok {
+			t.Errorf("tikzColor(%q) = %q, %q, %v, want %q, %q, %v", tt.color, name, rgb, ok, tt.name, tt.rgb, tt.ok)
+		}
+	}
+}
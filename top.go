@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+// writeTop writes the n packages of the graph imported by the most packages,
+// with the number of packages importing them.
+func writeTop(w io.Writer, g *graph, n int) error {
+	importers := g.importers()
+	var paths []string
+	for _, node := range g.Nodes {
+		if len(importers[node.ImportPath]) > 0 {
+			paths = append(paths, node.ImportPath)
+		}
+	}
+	sort.SliceStable(paths, func(i, j int) bool {
+		return len(importers[paths[i]]) > len(importers[paths[j]])
+	})
+	if len(paths) > n {
+		paths = paths[:n]
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "PACKAGE\tIMPORTERS")
+	for _, p := range paths {
+		fmt.Fprintf(tw, "%s\t%d\n", processName(p), len(importers[p]))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// writeTree writes the imports of each root package as an indented text tree.
+// The imports of a package are only expanded the first time it appears, and
+// imports that close a cycle are marked as such.
+func writeTree(w io.Writer, g *graph) error {
+	expanded := make(map[string]bool)
+	onPath := make(map[string]bool)
+
+	var walk func(n *node, prefix string)
+	walk = func(n *node, prefix string) {
+		expanded[n.ImportPath] = true
+		onPath[n.ImportPath] = true
+		for i, imp := range n.Imports {
+			branch, indent := "|-- ", "|   "
+			if i == len(n.Imports)-1 {
+				branch, indent = "`-- ", "    "
+			}
+			impNode := g.node(imp)
+			switch {
+			case onPath[imp]:
+				fmt.Fprintf(w, "%s%s%s (cycle)\n", prefix, branch, processName(imp))
+			case expanded[imp] && len(impNode.Imports) > 0:
+				fmt.Fprintf(w, "%s%s%s (*)\n", prefix, branch, processName(imp))
+			default:
+				fmt.Fprintf(w, "%s%s%s\n", prefix, branch, processName(imp))
+				walk(impNode, prefix+indent)
+			}
+		}
+		onPath[n.ImportPath] = false
+	}
+
+	for _, root := range g.Roots {
+		n := g.node(root)
+		if _, err := fmt.Fprintln(w, processName(root)); err != nil {
+			return err
+		}
+		if expanded[root] && len(n.Imports) > 0 {
+			fmt.Fprintln(w, "(*)")
+			continue
+		}
+		walk(n, "")
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// modModes are the values accepted by -mod, as by the go command.
+var modModes = map[string]bool{
+	"mod":      true,
+	"readonly": true,
+	"vendor":   true,
+}
+
+// setModMode makes the go command used by go/build to resolve packages in
+// module mode use the given -mod mode.
+func setModMode(mode string) error {
+	if !modModes[mode] {
+		return fmt.Errorf("unknown -mod mode: %s", mode)
+	}
+	return os.Setenv("GOFLAGS", strings.TrimSpace(os.Getenv("GOFLAGS")+" -mod="+mode))
+}
+
+// checkVendored returns an error if a package that isn't part of the standard
+// library or of the main module, or of the directory tree being scanned
+// outside of module mode, wasn't found in a vendor directory.
+func checkVendored(dir, pkgDir string, goroot bool) error {
+	if goroot || strings.Contains(filepath.ToSlash(pkgDir), "/vendor/") {
+		return nil
+	}
+	mainDir := dir
+	if m := moduleOf(dir); m != nil && m.Version == "" {
+		mainDir = m.Dir
+	}
+	if rel, err := filepath.Rel(mainDir, pkgDir); err == nil && !strings.HasPrefix(rel, "..") {
+		return nil
+	}
+	return fmt.Errorf("not found in the vendor directory but in %s", pkgDir)
+}
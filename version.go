@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+)
+
+// writeVersion writes the version of godepgraph, the commit it was built
+// from, and the Go version it was built with, as recorded in its build info.
+func writeVersion(w io.Writer) {
+	version, commit := "(devel)", ""
+	var modified bool
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if v := info.Main.Version; v != "" {
+			version = v
+		}
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				commit = s.Value
+			case "vcs.modified":
+				modified = s.Value == "true"
+			}
+		}
+	}
+	fmt.Fprintf(w, "godepgraph %s\n", version)
+	if commit != "" {
+		if modified {
+			commit += " (modified)"
+		}
+		fmt.Fprintf(w, "commit %s\n", commit)
+	}
+	fmt.Fprintf(w, "built with %s\n", runtime.Version())
+}
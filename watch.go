@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"time"
+)
+
+// watchInterval is how often the watched files are checked for changes.
+const watchInterval = time.Second
+
+// watchPackages writes the output like run, then again each time one of the
+// files of the scanned packages outside of the standard library, or the
+// go.mod file of their module, changes. Errors are logged and the files are
+// watched again, except when nothing could be scanned yet.
+func watchPackages(cwd string, args []string, write func(io.Writer, *graph) error) error {
+	var watched map[string]map[string]fileStamp
+	for {
+		out, err := createOutput(*outputFile)
+		if err != nil {
+			return fmt.Errorf("failed to create output: %s", err)
+		}
+		g, _, err := run(cwd, args, out, write)
+		switch {
+		case err != nil && watched == nil:
+			return err
+		case err != nil:
+			log.Print(err)
+		default:
+			watched = watchedFiles(g)
+			logf(levelInfo, "wrote %s, watching %d package(s) for changes", *outputFile, len(watched))
+		}
+		for unchanged(watched) {
+			time.Sleep(watchInterval)
+		}
+	}
+}
+
+// watchedFiles returns the stamps of the files in the directories of the
+// packages of the graph outside of the standard library.
+func watchedFiles(g *graph) map[string]map[string]fileStamp {
+	watched := make(map[string]map[string]fileStamp)
+	for _, n := range g.Nodes {
+		if n.Goroot || n.Dir == "" {
+			continue
+		}
+		if stamps := dirStamps(n.Dir, false); stamps != nil {
+			watched[n.Dir] = stamps
+		}
+	}
+	return watched
+}
+
+// unchanged reports whether none of the watched files changed.
+func unchanged(watched map[string]map[string]fileStamp) bool {
+	for dir, stamps := range watched {
+		if !sameFiles(dir, stamps, false) {
+			logf(levelVerbose, "%s changed", dir)
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// writeWhy writes the shortest import chain from the root packages to the
+// given package, with the position of each import statement along the way.
+func writeWhy(w io.Writer, g *graph, to string) error {
+	if err := g.checkNodes([]string{to}); err != nil {
+		return err
+	}
+	if len(g.Roots) == 0 {
+		return fmt.Errorf("the root package isn't part of the graph, so there is no import chain to %s", to)
+	}
+	path := g.shortestPath(g.Roots, to)
+	if path == nil {
+		return fmt.Errorf("%s is not imported by %s", to, g.Roots[0])
+	}
+
+	fmt.Fprintln(w, processName(path[0]))
+	for i := 1; i < len(path); i++ {
+		pos := g.node(path[i-1]).importPos[path[i]]
+		if _, err := fmt.Fprintf(w, "%*simports %s%s\n", 2*i, "", processName(path[i]), posSuffix(pos)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// posSuffix returns the position of an import statement to append to a line
+// of output, or nothing if it isn't known, as with -golist.
+func posSuffix(pos token.Position) string {
+	if pos.Filename == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (%s:%d)", relativePath(pos.Filename), pos.Line)
+}
+
+// relativePath returns file relative to the current directory if that is
+// shorter.
+func relativePath(file string) string {
+	cwd, _ := os.Getwd()
+	if rel, err := filepath.Rel(cwd, file); err == nil && len(rel) < len(file) {
+		return rel
+	}
+	return file
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// without returns the graph as if the given packages didn't exist, along
+// with the imports given as from=to, keeping only the packages that the root
+// packages still depend on. It also returns the packages that are no longer
+// depended on, other than the removed ones.
+func (g *graph) without(items []string) (*graph, []string, error) {
+	removedNodes := make(map[string]bool)
+	removedEdges := make(map[[2]string]bool)
+	for _, item := range items {
+		if kv := strings.SplitN(item, "=", 2); len(kv) == 2 {
+			if !g.hasEdge(kv[0], kv[1]) {
+				return nil, nil, fmt.Errorf("%s doesn't import %s", kv[0], kv[1])
+			}
+			removedEdges[[2]string{kv[0], kv[1]}] = true
+			continue
+		}
+		if err := g.checkNodes([]string{item}); err != nil {
+			return nil, nil, err
+		}
+		removedNodes[item] = true
+	}
+
+	var roots []string
+	for _, root := range g.Roots {
+		if !removedNodes[root] {
+			roots = append(roots, root)
+		}
+	}
+	before := g.reachable(g.Roots)
+	after := g.reachableExcept(roots, func(from, to string) bool {
+		return removedNodes[to] || removedEdges[[2]string{from, to}]
+	})
+	var gone []string
+	for _, n := range g.Nodes {
+		if before[n.ImportPath] && !after[n.ImportPath] && !removedNodes[n.ImportPath] {
+			gone = append(gone, n.ImportPath)
+		}
+	}
+	return g.subgraph(func(n *node) bool {
+		return after[n.ImportPath]
+	}, func(from, to string) bool {
+		return !removedEdges[[2]string{from, to}]
+	}), gone, nil
+}
+
+// reportWithout writes a summary of the packages that are no longer depended
+// on without the packages and imports given to -without.
+func reportWithout(w io.Writer, items, gone []string) {
+	if len(gone) == 0 {
+		fmt.Fprintf(w, "without %s, no other package is dropped\n", strings.Join(items, ", "))
+		return
+	}
+	fmt.Fprintf(w, "without %s, %d other package(s) are dropped:\n", strings.Join(items, ", "), len(gone))
+	for _, p := range gone {
+		fmt.Fprintf(w, "  %s\n", processName(p))
+	}
+}
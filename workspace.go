@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// workspaceModules holds the modules of the workspace when -workspace is
+// given, whose packages are clustered in the dot format.
+var workspaceModules []*module
+
+// localModules holds the modules of the workspace containing the scanned
+// directory, other than the module containing the directory itself.
+var localModules map[string]bool
+
+// workspaceUses returns the modules of the workspace containing dir, other
+// than the one containing dir, or nil if there is no workspace.
+func workspaceUses(dir string) map[string]bool {
+	work := findWorkspace(dir)
+	if work == "" {
+		return nil
+	}
+	mods, err := readWorkspace(work)
+	if err != nil {
+		return nil
+	}
+	uses := make(map[string]bool)
+	for _, m := range mods {
+		uses[m.Path] = true
+	}
+	if m := moduleOf(dir); m != nil {
+		delete(uses, m.Path)
+	}
+	return uses
+}
+
+// findWorkspace returns the go.work file used by the go command in dir: the
+// one given by GOWORK, or the first one found in dir and its parents. It
+// returns "" if workspace mode is off or there is none.
+func findWorkspace(dir string) string {
+	switch gowork := os.Getenv("GOWORK"); gowork {
+	case "off":
+		return ""
+	case "":
+	default:
+		return gowork
+	}
+	for d := dir; ; {
+		if _, err := os.Stat(filepath.Join(d, "go.work")); err == nil {
+			return filepath.Join(d, "go.work")
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return ""
+		}
+		d = parent
+	}
+}
+
+// readWorkspace returns the modules listed in the use directives of the named
+// go.work file.
+func readWorkspace(name string) ([]*module, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var dirs []string
+	inUse := false
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case inUse && line == ")":
+			inUse = false
+		case inUse && line != "":
+			dirs = append(dirs, strings.Trim(line, `"`))
+		case line == "use (":
+			inUse = true
+		case strings.HasPrefix(line, "use "):
+			dirs = append(dirs, strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "use ")), `"`))
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	var mods []*module
+	for _, d := range dirs {
+		if !filepath.IsAbs(d) {
+			d = filepath.Join(filepath.Dir(name), filepath.FromSlash(d))
+		}
+		path := modulePath(filepath.Join(d, "go.mod"))
+		if path == "" {
+			return nil, fmt.Errorf("%s: no module in %s", name, d)
+		}
+		mods = append(mods, &module{Path: path, Dir: d})
+	}
+	return mods, nil
+}
+
+// workspacePackages returns the import paths of all the packages of the
+// modules of the workspace containing dir.
+func workspacePackages(dir string) ([]*module, []string, error) {
+	work := findWorkspace(dir)
+	if work == "" {
+		return nil, nil, fmt.Errorf("no go.work file found in %s or its parents", dir)
+	}
+	mods, err := readWorkspace(work)
+	if err != nil {
+		return nil, nil, err
+	}
+	var paths []string
+	for _, m := range mods {
+		found, err := treePackages(m.Dir, m.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, pkg := range found {
+			paths = append(paths, pkg.ImportPath)
+		}
+	}
+	return mods, paths, nil
+}
+
+// workspaceModuleOf returns the path of the workspace module containing the
+// package, or "" if it isn't part of one.
+func workspaceModuleOf(n *node) string {
+	for _, m := range workspaceModules {
+		if n.Module == m.Path {
+			return m.Path
+		}
+	}
+	return ""
+}
@@ -0,0 +1,18 @@
+package main
+
+import "testing"
+
+func TestRGBColor(t *testing.T) {
+	tests := []struct{ color, want string }{
+		{"darkgoldenrod1", "#ffb90f"},
+		{"LightGoldenrod1", "#ffec8b"},
+		{"misty rose", "#ffe4e1"},
+		{"#98fb98", "#98fb98"},
+		{"nocolor", "nocolor"},
+	}
+	for _, tt := range tests {
+		if got := rgbColor(tt.color); got != tt.want {
+			t.Errorf("rgbColor(%q) = %q, want %q", tt.color, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// writeYAML writes the graph as a YAML document with the same structure as the
+// JSON output.
+func writeYAML(w io.Writer, g *graph) error {
+	if len(g.Nodes) == 0 {
+		fmt.Fprintln(w, "nodes: []")
+	} else {
+		fmt.Fprintln(w, "nodes:")
+	}
+	for _, n := range g.Nodes {
+		fmt.Fprintf(w, "  - importPath: %s\n", yamlString(n.ImportPath))
+		fmt.Fprintf(w, "    dir: %s\n", yamlString(n.Dir))
+		fmt.Fprintf(w, "    goroot: %t\n", n.Goroot)
+		fmt.Fprintf(w, "    cgo: %t\n", n.Cgo)
+		fmt.Fprintf(w, "    vendored: %t\n", n.Vendored)
+		for _, f := range []struct{ key, value string }{
+			{"module", n.Module},
+			{"version", n.Version},
+			{"replace", n.Replace},
+		} {
+			if f.value != "" {
+				fmt.Fprintf(w, "    %s: %s\n", f.key, yamlString(f.value))
+			}
+		}
+		if n.Local {
+			fmt.Fprintln(w, "    local: true")
+		}
+		if n.Indirect {
+			fmt.Fprintln(w, "    indirect: true")
+		}
+		if n.Error != "" {
+			fmt.Fprintf(w, "    error: %s\n", yamlString(n.Error))
+		}
+		if n.Lines != 0 {
+			fmt.Fprintf(w, "    lines: %d\n", n.Lines)
+		}
+		if len(n.Members) > 0 {
+			fmt.Fprintln(w, "    members:")
+			for _, m := range n.Members {
+				fmt.Fprintf(w, "      - %s\n", yamlString(m))
+			}
+		}
+	}
+
+	edges := g.edges()
+	if len(edges) == 0 {
+		fmt.Fprintln(w, "edges: []")
+	} else {
+		fmt.Fprintln(w, "edges:")
+	}
+	for _, e := range edges {
+		fmt.Fprintf(w, "  - from: %s\n", yamlString(e.From))
+		fmt.Fprintf(w, "    to: %s\n", yamlString(e.To))
+		fmt.Fprintf(w, "    type: %s\n", e.Type)
+		if _, err := fmt.Fprintf(w, "    weight: %d\n", e.Weight); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var yamlPlain = regexp.MustCompile(`^[A-Za-z0-9_./][A-Za-z0-9_./~+-]*$`)
+
+// yamlString returns s as a YAML scalar, quoting it only if it could be
+// mistaken for something other than a plain string.
+func yamlString(s string) string {
+	if !yamlPlain.MatchString(s) {
+		return strconv.Quote(s)
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "y", "n":
+		return strconv.Quote(s)
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return strconv.Quote(s)
+	}
+	return s
+}